@@ -0,0 +1,59 @@
+package gosc
+
+/**
+ * Tests for validation of OSC addresses and address patterns.
+ */
+
+import (
+	"bytes"
+	"strings"
+	. "testing"
+)
+
+func expectArgumentError(t *T, err error, contains string) {
+	if e, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError, got %#v (%T)", err, err)
+	} else if !strings.Contains(e.Error(), contains) {
+		t.Errorf("expected error containing %q, got %q", contains, e.Error())
+	}
+}
+
+func TestOSCAddressValid(t *T) {
+	expectNil(t, OSCAddress("/mixer/3/mute").Valid())
+	expectNil(t, OSCAddress("/").Valid())
+
+	expectArgumentError(t, OSCAddress("mixer").Valid(), "forward slash")
+	expectArgumentError(t, OSCAddress("").Valid(), "forward slash")
+	expectArgumentError(t, OSCAddress("/mixer/*/mute").Valid(), "'*' found at position 7")
+	expectArgumentError(t, OSCAddress("/mixer/[12]").Valid(), "'[' found at position 7")
+	expectArgumentError(t, OSCAddress("/a b").Valid(), "' ' found at position 2")
+	expectArgumentError(t, OSCAddress("/mixer//mute").Valid(), "position 7")
+}
+
+func TestOSCAddressPatternValid(t *T) {
+	expectNil(t, OSCAddressPattern("/mixer/3/mute").Valid())
+	expectNil(t, OSCAddressPattern("/mixer/*/mute").Valid())
+	expectNil(t, OSCAddressPattern("/mixer/[0-9]/{mute,solo}").Valid())
+	expectNil(t, OSCAddressPattern("/mixer/[!a]?").Valid())
+	expectNil(t, OSCAddressPattern("//mute").Valid())
+
+	expectArgumentError(t, OSCAddressPattern("mixer").Valid(), "forward slash")
+	expectArgumentError(t, OSCAddressPattern("/a,b").Valid(), "',' found at position 2")
+	expectArgumentError(t, OSCAddressPattern("/a#b").Valid(), "'#' found at position 2")
+	expectArgumentError(t, OSCAddressPattern("/mixer/[12/mute").Valid(), "unterminated '[' at position 7")
+	expectArgumentError(t, OSCAddressPattern("/mixer/12]/mute").Valid(), "unmatched ']' at position 9")
+	expectArgumentError(t, OSCAddressPattern("/mixer/{a,b/mute").Valid(), "unterminated '{' at position 7")
+	expectArgumentError(t, OSCAddressPattern("/mixer/a}").Valid(), "unmatched '}' at position 8")
+}
+
+func TestWriteMessageWildcardAddress(t *T) {
+	var out bytes.Buffer
+
+	_, err := WriteMessage(&out, OSCAddressPattern("/mixer/*/mute"), OSCInt32(1))
+	expectNil(t, err)
+
+	address, args, err := ReadMessage(&out)
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/mixer/*/mute"), address)
+	expectSame(t, []OSCArg{OSCInt32(1)}, args)
+}
@@ -5,10 +5,12 @@ package gosc
  */
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 	"math"
 	. "testing"
+	"testing/iotest"
 )
 
 func TestReadOSCInt32(t *T) {
@@ -108,3 +110,101 @@ func TestReadOSCBlob(t *T) {
 	expectNil(t, err)
 	expectSame(t, b, OSCBlob([]byte{1,2,3,4,5}))
 }
+
+func TestReadOSCInt64(t *T) {
+	i, err := ReadOSCInt64(bytes.NewReader([]byte{0xff,0xff,0xff,0xff,0xff,0xff,0xff,0xfe}))
+	expectNil(t, err)
+	expectSame(t, OSCInt64(-2), i)
+
+	_, err = ReadOSCInt64(bytes.NewReader([]byte{0,0,0,1}))
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError for a truncated int64, got %#v", err)
+	}
+}
+
+func TestReadOSCFloat64(t *T) {
+	f, err := ReadOSCFloat64(bytes.NewReader([]byte{0xc0,0x04,0,0,0,0,0,0}))
+	expectNil(t, err)
+	expectSame(t, OSCFloat64(-2.5), f)
+}
+
+func TestReadOSCTimetag(t *T) {
+	tt, err := ReadOSCTimetag(bytes.NewReader([]byte{0,0,0,2,0x80,0,0,0}))
+	expectNil(t, err)
+	expectSame(t, OSCTimetag(2<<32 | 0x80000000), tt)
+}
+
+func TestReadOSCCharRGBAMIDI(t *T) {
+	c, err := ReadOSCChar(bytes.NewReader([]byte{0,0,0,'x'}))
+	expectNil(t, err)
+	expectSame(t, OSCChar('x'), c)
+
+	r, err := ReadOSCRGBA(bytes.NewReader([]byte{1,2,3,4}))
+	expectNil(t, err)
+	expectSame(t, OSCRGBA{1, 2, 3, 4}, r)
+
+	m, err := ReadOSCMIDI(bytes.NewReader([]byte{0,0x90,60,127}))
+	expectNil(t, err)
+	expectSame(t, OSCMIDI{0, 0x90, 60, 127}, m)
+}
+
+func TestReadOSCBlobPadding(t *T) {
+	for size := 0; size <= 8; size++ {
+		var in bytes.Buffer
+
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i + 1)
+		}
+
+		OSCBlob(data).WriteTo(&in)
+		OSCInt32(42).WriteTo(&in)
+
+		b, err := ReadOSCBlob(&in)
+		expectNil(t, err)
+		expectSame(t, OSCBlob(data), b)
+
+		// The padding was consumed, so the next argument is aligned.
+		i, err := ReadOSCInt32(&in)
+		expectNil(t, err)
+		expectSame(t, OSCInt32(42), i)
+	}
+}
+
+func TestReadOSCBlobShortReads(t *T) {
+	input := []byte{0,0,0,5,1,2,3,4,5,0,0,0}
+
+	b, err := ReadOSCBlob(iotest.OneByteReader(bytes.NewReader(input)))
+	expectNil(t, err)
+	expectSame(t, OSCBlob([]byte{1,2,3,4,5}), b)
+
+	b, err = ReadOSCBlob(bufio.NewReaderSize(iotest.HalfReader(bytes.NewReader(input)), 16))
+	expectNil(t, err)
+	expectSame(t, OSCBlob([]byte{1,2,3,4,5}), b)
+}
+
+func TestReadOSCBlobErrors(t *T) {
+	inputs := map[string][]byte{
+		"truncated data": []byte{0,0,0,5,1,2,3},
+		"truncated padding": []byte{0,0,0,5,1,2,3,4,5,0},
+		"bad padding": []byte{0,0,0,5,1,2,3,4,5,0,1,0},
+		"negative size": []byte{0xff,0xff,0xff,0xff},
+		"huge size": []byte{0x7f,0xff,0xff,0xff},
+	}
+
+	for name, input := range inputs {
+		_, err := ReadOSCBlob(bytes.NewReader(input))
+		if _, ok := err.(OSCReadError); !ok {
+			t.Errorf("%s: expected an OSCReadError, got %#v", name, err)
+		}
+	}
+
+	_, err := ReadOSCBlobLimit(bytes.NewReader([]byte{0,0,0,8,1,2,3,4,5,6,7,8}), 4)
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError for a blob over the limit, got %#v", err)
+	}
+
+	b, err := ReadOSCBlobLimit(bytes.NewReader([]byte{0,0,0,4,1,2,3,4}), 4)
+	expectNil(t, err)
+	expectSame(t, OSCBlob([]byte{1,2,3,4}), b)
+}
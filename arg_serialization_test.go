@@ -205,3 +205,129 @@ func TestWriteOSCBlob(t *T) {
 	expectSame(t, []byte{0,0,0,5,1,2,3,4,5,0,0,0}, out.Bytes())
 	out.Reset()
 }
+
+func TestWriteOSCInt64(t *T) {
+	var out bytes.Buffer
+	var i OSCInt64
+	var err error
+	var n int
+
+	i = 42
+	n, err = i.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 8, n)
+	expectSame(t, []byte{0,0,0,0,0,0,0,0x2a}, out.Bytes())
+	out.Reset()
+
+	i = math.MinInt64
+	n, err = i.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 8, n)
+	expectSame(t, []byte{0x80,0,0,0,0,0,0,0}, out.Bytes())
+	out.Reset()
+}
+
+func TestWriteOSCFloat64(t *T) {
+	var out bytes.Buffer
+	var f OSCFloat64
+	var err error
+	var n int
+
+	f = 1.0
+	n, err = f.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 8, n)
+	expectSame(t, []byte{0x3f,0xf0,0,0,0,0,0,0}, out.Bytes())
+	out.Reset()
+
+	f = -2.5
+	n, err = f.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 8, n)
+	expectSame(t, []byte{0xc0,0x04,0,0,0,0,0,0}, out.Bytes())
+	out.Reset()
+}
+
+func TestWriteOSCTimetag(t *T) {
+	var out bytes.Buffer
+	var err error
+	var n int
+
+	n, err = OSCTimetag(1).WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 8, n)
+	expectSame(t, []byte{0,0,0,0,0,0,0,1}, out.Bytes())
+	out.Reset()
+}
+
+func TestWriteOSCCharRGBAMIDI(t *T) {
+	var out bytes.Buffer
+	var err error
+	var n int
+
+	n, err = OSCChar('x').WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 4, n)
+	expectSame(t, []byte{0,0,0,'x'}, out.Bytes())
+	out.Reset()
+
+	if _, ok := OSCChar(0xc9).Valid().(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for a non-ascii char")
+	}
+
+	n, err = OSCRGBA{0x10, 0x20, 0x30, 0xff}.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 4, n)
+	expectSame(t, []byte{0x10,0x20,0x30,0xff}, out.Bytes())
+	out.Reset()
+
+	n, err = OSCMIDI{0, 0x90, 60, 127}.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 4, n)
+	expectSame(t, []byte{0,0x90,60,127}, out.Bytes())
+	out.Reset()
+}
+
+func TestWriteOSCNoDataTypes(t *T) {
+	var out bytes.Buffer
+
+	for _, arg := range []OSCArg{OSCBool(true), OSCBool(false), OSCNil{}, OSCInfinity{}} {
+		n, err := arg.WriteTo(&out)
+		expectNil(t, err)
+		expectSame(t, 0, n)
+	}
+
+	expectSame(t, 0, out.Len())
+	expectSame(t, OSC_ETYPE_TRUE, OSCBool(true).Tag())
+	expectSame(t, OSC_ETYPE_FALSE, OSCBool(false).Tag())
+}
+
+func TestWriteOSCArray(t *T) {
+	var out bytes.Buffer
+	var a OSCArray
+	var err error
+	var n int
+
+	a = OSCArray{}
+	expectNil(t, a.Valid())
+	expectSame(t, "[]", a.TypeTags())
+	n, err = a.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 0, n)
+	expectSame(t, 0, out.Len())
+	out.Reset()
+
+	a = OSCArray{OSCInt32(1), OSCArray{OSCString("ab"), OSCBool(true)}, OSCFloat32(0)}
+	expectNil(t, a.Valid())
+	expectSame(t, "[i[sT]f]", a.TypeTags())
+	n, err = a.WriteTo(&out)
+	expectNil(t, err)
+	expectSame(t, 12, n)
+	expectSame(t, []byte{0,0,0,1, 97,98,0,0, 0,0,0,0}, out.Bytes())
+	out.Reset()
+
+	a = OSCArray{OSCArray{nil}}
+	if _, ok := a.Valid().(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for a nil array element")
+	}
+}
@@ -0,0 +1,238 @@
+package gosc
+
+import (
+	"math"
+)
+
+// Args wraps the arguments of a received message with typed accessors, e.g.:
+//
+//	address, args, err := ReadMessage(in)
+//	level, err := Args(args).Float(0)
+//
+// Each accessor returns an error if the index is out of range or the argument
+// has a different type. Conversions that can't lose information are allowed
+// (Int64 accepts an int32, Float64 a float32); for anything looser, see
+// Lenient.
+type Args []OSCArg
+
+// LenientArgs has the same accessors as Args, but coerces between compatible
+// types: integers and floats convert to each other (as long as the value is in
+// range), integers convert to bool (non-zero is true), and alternate strings
+// ('S') are accepted as strings.
+type LenientArgs []OSCArg
+
+// Lenient returns a view of the arguments that coerces between types.
+func (a Args) Lenient() LenientArgs {
+	return LenientArgs(a)
+}
+
+func (a Args) Int32(i int) (int32, error) { return argAsInt32(a, i, false) }
+func (a Args) Int64(i int) (int64, error) { return argAsInt64(a, i, false) }
+func (a Args) Float(i int) (float32, error) { return argAsFloat32(a, i, false) }
+func (a Args) Float64(i int) (float64, error) { return argAsFloat64(a, i, false) }
+func (a Args) String(i int) (string, error) { return argAsString(a, i, false) }
+func (a Args) Bool(i int) (bool, error) { return argAsBool(a, i, false) }
+func (a Args) Blob(i int) ([]byte, error) { return argAsBlob(a, i) }
+func (a Args) Timetag(i int) (OSCTimetag, error) { return argAsTimetag(a, i) }
+
+func (a LenientArgs) Int32(i int) (int32, error) { return argAsInt32(a, i, true) }
+func (a LenientArgs) Int64(i int) (int64, error) { return argAsInt64(a, i, true) }
+func (a LenientArgs) Float(i int) (float32, error) { return argAsFloat32(a, i, true) }
+func (a LenientArgs) Float64(i int) (float64, error) { return argAsFloat64(a, i, true) }
+func (a LenientArgs) String(i int) (string, error) { return argAsString(a, i, true) }
+func (a LenientArgs) Bool(i int) (bool, error) { return argAsBool(a, i, true) }
+func (a LenientArgs) Blob(i int) ([]byte, error) { return argAsBlob(a, i) }
+func (a LenientArgs) Timetag(i int) (OSCTimetag, error) { return argAsTimetag(a, i) }
+
+// Returns the argument at index i, or an error if there isn't one.
+func argAt(args []OSCArg, i int) (OSCArg, error) {
+	if i < 0 || i >= len(args) {
+		return nil, OSCArgumentErrorf("argument %d out of range (%d arguments)", i, len(args))
+	}
+
+	if args[i] == nil {
+		return nil, OSCArgumentErrorf("argument %d is nil", i)
+	}
+
+	return args[i], nil
+}
+
+func argTypeError(arg OSCArg, i int, want string) error {
+	return OSCArgumentErrorf("argument %d is %s, not %s", i, tagName(arg.Tag()), want)
+}
+
+func argAsInt64(args []OSCArg, i int, lenient bool) (int64, error) {
+	return argAsInteger(args, i, lenient, "int64")
+}
+
+// Reads an integer argument, naming the requested type in errors.
+func argAsInteger(args []OSCArg, i int, lenient bool, want string) (int64, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return 0, err
+	}
+
+	if v, ok := argInt(arg); ok {
+		return v, nil
+	}
+
+	if lenient {
+		if f, ok := argFloat(arg); ok {
+			// Truncated toward zero, like a Go conversion.
+			if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return 0, OSCArgumentErrorf("argument %d (%g) overflows int64", i, f)
+			}
+			return int64(f), nil
+		}
+	}
+
+	return 0, argTypeError(arg, i, want)
+}
+
+func argAsInt32(args []OSCArg, i int, lenient bool) (int32, error) {
+	if !lenient {
+		arg, err := argAt(args, i)
+		if err != nil {
+			return 0, err
+		}
+		if v, ok := arg.(OSCInt32); ok {
+			return int32(v), nil
+		}
+		return 0, argTypeError(arg, i, "int32")
+	}
+
+	v, err := argAsInteger(args, i, true, "int32")
+	if err != nil {
+		return 0, err
+	}
+
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, OSCArgumentErrorf("argument %d (%d) overflows int32", i, v)
+	}
+
+	return int32(v), nil
+}
+
+func argAsFloat64(args []OSCArg, i int, lenient bool) (float64, error) {
+	return argAsNumber(args, i, lenient, "float64")
+}
+
+// Reads a float argument, naming the requested type in errors.
+func argAsNumber(args []OSCArg, i int, lenient bool, want string) (float64, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return 0, err
+	}
+
+	if f, ok := argFloat(arg); ok {
+		return f, nil
+	}
+
+	if lenient {
+		if v, ok := argInt(arg); ok {
+			return float64(v), nil
+		}
+	}
+
+	return 0, argTypeError(arg, i, want)
+}
+
+func argAsFloat32(args []OSCArg, i int, lenient bool) (float32, error) {
+	if !lenient {
+		arg, err := argAt(args, i)
+		if err != nil {
+			return 0, err
+		}
+		if v, ok := arg.(OSCFloat32); ok {
+			return float32(v), nil
+		}
+		return 0, argTypeError(arg, i, "float32")
+	}
+
+	v, err := argAsNumber(args, i, true, "float32")
+	if err != nil {
+		return 0, err
+	}
+
+	// Infinities convert exactly; finite values too large for a float32
+	// would silently become infinite.
+	if !math.IsInf(v, 0) && math.Abs(v) > math.MaxFloat32 {
+		return 0, OSCArgumentErrorf("argument %d (%g) overflows float32", i, v)
+	}
+
+	return float32(v), nil
+}
+
+func argAsString(args []OSCArg, i int, lenient bool) (string, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return "", err
+	}
+
+	switch a := arg.(type) {
+	case OSCString:
+		return string(a), nil
+	case OSCStringAlt:
+		if lenient {
+			return string(a), nil
+		}
+	}
+
+	return "", argTypeError(arg, i, "string")
+}
+
+func argAsBool(args []OSCArg, i int, lenient bool) (bool, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return false, err
+	}
+
+	if b, ok := arg.(OSCBool); ok {
+		return bool(b), nil
+	}
+
+	if lenient {
+		if v, ok := argInt(arg); ok {
+			return v != 0, nil
+		}
+	}
+
+	return false, argTypeError(arg, i, "bool")
+}
+
+func argAsBlob(args []OSCArg, i int) ([]byte, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := arg.(OSCBlob); ok {
+		return []byte(b), nil
+	}
+
+	return nil, argTypeError(arg, i, "blob")
+}
+
+func argAsTimetag(args []OSCArg, i int) (OSCTimetag, error) {
+	arg, err := argAt(args, i)
+	if err != nil {
+		return 0, err
+	}
+
+	if t, ok := arg.(OSCTimetag); ok {
+		return t, nil
+	}
+
+	return 0, argTypeError(arg, i, "timetag")
+}
+
+func argFloat(arg OSCArg) (float64, bool) {
+	switch a := arg.(type) {
+	case OSCFloat32:
+		return float64(a), true
+	case OSCFloat64:
+		return float64(a), true
+	}
+
+	return 0, false
+}
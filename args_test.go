@@ -0,0 +1,137 @@
+package gosc
+
+import (
+	"math"
+	. "testing"
+)
+
+var argsTestArgs = Args{
+	OSCInt32(3), OSCFloat32(0.5), OSCString("s"), OSCBool(true), OSCInt64(1 << 40),
+	OSCFloat64(2.5), OSCStringAlt("S"), OSCBlob([]byte{1}), OSC_TIMETAG_IMMEDIATELY, OSCInt32(0),
+}
+
+func TestArgs(t *T) {
+	a := argsTestArgs
+
+	i, err := a.Int32(0)
+	expectNil(t, err)
+	expectSame(t, int32(3), i)
+
+	f, err := a.Float(1)
+	expectNil(t, err)
+	expectSame(t, float32(0.5), f)
+
+	s, err := a.String(2)
+	expectNil(t, err)
+	expectSame(t, "s", s)
+
+	b, err := a.Bool(3)
+	expectNil(t, err)
+	expectSame(t, true, b)
+
+	// Lossless widening is always allowed.
+	h, err := a.Int64(0)
+	expectNil(t, err)
+	expectSame(t, int64(3), h)
+
+	d, err := a.Float64(1)
+	expectNil(t, err)
+	expectSame(t, 0.5, d)
+
+	blob, err := a.Blob(7)
+	expectNil(t, err)
+	expectSame(t, []byte{1}, blob)
+
+	tt, err := a.Timetag(8)
+	expectNil(t, err)
+	expectSame(t, OSC_TIMETAG_IMMEDIATELY, tt)
+}
+
+func TestArgsErrors(t *T) {
+	a := argsTestArgs
+
+	_, err := a.Int32(10)
+	expectArgumentError(t, err, "argument 10 out of range (10 arguments)")
+
+	_, err = a.Int32(-1)
+	expectArgumentError(t, err, "out of range")
+
+	_, err = a.Int32(1)
+	expectArgumentError(t, err, "argument 1 is float32, not int32")
+
+	_, err = a.Int32(4)
+	expectArgumentError(t, err, "argument 4 is int64, not int32")
+
+	_, err = a.Float(0)
+	expectArgumentError(t, err, "argument 0 is int32, not float32")
+
+	_, err = a.String(6)
+	expectArgumentError(t, err, "argument 6 is alternate string, not string")
+
+	_, err = a.Bool(9)
+	expectArgumentError(t, err, "argument 9 is int32, not bool")
+
+	_, err = Args{nil}.Bool(0)
+	expectArgumentError(t, err, "argument 0 is nil")
+}
+
+func TestLenientArgs(t *T) {
+	a := argsTestArgs.Lenient()
+
+	// A fader might send either an int or a float.
+	f, err := a.Float(0)
+	expectNil(t, err)
+	expectSame(t, float32(3), f)
+
+	i, err := a.Int32(1)
+	expectNil(t, err)
+	expectSame(t, int32(0), i)
+
+	i, err = a.Int32(5)
+	expectNil(t, err)
+	expectSame(t, int32(2), i)
+
+	s, err := a.String(6)
+	expectNil(t, err)
+	expectSame(t, "S", s)
+
+	b, err := a.Bool(0)
+	expectNil(t, err)
+	expectSame(t, true, b)
+
+	b, err = a.Bool(9)
+	expectNil(t, err)
+	expectSame(t, false, b)
+
+	_, err = a.Int32(4)
+	expectArgumentError(t, err, "overflows int32")
+
+	_, err = a.Bool(1)
+	expectArgumentError(t, err, "argument 1 is float32, not bool")
+
+	_, err = a.Int32(2)
+	expectArgumentError(t, err, "argument 2 is string, not int32")
+}
+
+func TestLenientArgsFloat32Range(t *T) {
+	a := Args{OSCFloat64(1e300), OSCFloat64(-1e300), OSCFloat64(math.Inf(1)), OSCFloat64(math.MaxFloat32), OSCInt64(1 << 62)}.Lenient()
+
+	_, err := a.Float(0)
+	expectArgumentError(t, err, "argument 0 (1e+300) overflows float32")
+
+	_, err = a.Float(1)
+	expectArgumentError(t, err, "overflows float32")
+
+	// Infinities aren't out of range, and neither is the largest float32.
+	f, err := a.Float(2)
+	expectNil(t, err)
+	expectSame(t, float32(math.Inf(1)), f)
+
+	f, err = a.Float(3)
+	expectNil(t, err)
+	expectSame(t, float32(math.MaxFloat32), f)
+
+	f, err = a.Float(4)
+	expectNil(t, err)
+	expectSame(t, float32(1 << 62), f)
+}
@@ -0,0 +1,197 @@
+package gosc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// The OSC-string that starts every bundle, in place of a message's address.
+const OSC_BUNDLE_TAG = OSCString("#bundle")
+
+// A packet is the unit of transmission in OSC; its contents are either a
+// single message (Message) or a bundle (OSCBundle).
+type OSCPacket interface {
+	// WriteTo writes the complete packet to an output stream, returning the
+	// number of bytes written.
+	WriteTo(out io.Writer) (int, error)
+
+	// Valid checks the packet and all of its contents, returning nil if it can
+	// be serialized correctly.
+	Valid() error
+}
+
+// A bundle is a timetag followed by any number of elements, each of which is
+// either a message or another bundle.
+type OSCBundle struct {
+	Timetag  OSCTimetag
+	Elements []OSCPacket
+}
+
+// Valid checks every element of the bundle, and ensures that the timetag of
+// any nested bundle is not earlier than the timetag of the enclosing bundle.
+func (b OSCBundle) Valid() error {
+	for i, elem := range b.Elements {
+		if elem == nil {
+			return OSCArgumentErrorf("nil element at position %d in bundle", i)
+		}
+
+		if nested, ok := elem.(OSCBundle); ok && nested.Timetag < b.Timetag {
+			return OSCArgumentErrorf("nested bundle timetag %d is earlier than enclosing bundle timetag %d", nested.Timetag, b.Timetag)
+		}
+
+		if err := elem.Valid(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTo writes the bundle to the output stream (see WriteBundle).
+func (b OSCBundle) WriteTo(out io.Writer) (int, error) {
+	return WriteBundle(out, b.Timetag, b.Elements...)
+}
+
+// Writes an OSC bundle to the output stream. Each element is preceded by its
+// size in bytes. Returns an error if the bundle or any of its elements were
+// invalid, or if any transmission error occurred, and returns the total number
+// of bytes sent in either case.
+func WriteBundle(out io.Writer, timetag OSCTimetag, elements...OSCPacket) (int, error) {
+	bundle := OSCBundle{timetag, elements}
+	if err := bundle.Valid(); err != nil {
+		return 0, err
+	}
+
+	// Element sizes have to be known before the elements are written, so each
+	// element is serialized in full before sending anything.
+	var body bytes.Buffer
+	for _, elem := range elements {
+		var data bytes.Buffer
+		if _, err := elem.WriteTo(&data); err != nil {
+			return 0, err
+		}
+
+		OSCInt32(data.Len()).WriteTo(&body)
+		body.Write(data.Bytes())
+	}
+
+	total := 0
+
+	if sent, err := OSC_BUNDLE_TAG.WriteTo(out); err != nil {
+		return sent, err
+	} else {
+		total += sent
+	}
+
+	if sent, err := timetag.WriteTo(out); err != nil {
+		return total+sent, err
+	} else {
+		total += sent
+	}
+
+	sent, err := out.Write(body.Bytes())
+	return total+sent, err
+}
+
+// Reads an OSC bundle from an input stream. Elements are read until the end of
+// the input, so the reader should contain exactly one packet (e.g. a single
+// UDP datagram).
+func ReadBundle(in io.Reader) (OSCBundle, error) {
+	tag, err := ReadOSCString(in)
+	if err != nil {
+		return OSCBundle{}, err
+	}
+
+	if tag != OSC_BUNDLE_TAG {
+		return OSCBundle{}, OSCReadErrorf("expected \"%s\", got \"%s\"", OSC_BUNDLE_TAG, tag)
+	}
+
+	return readBundleBody(in, 0, DecodeOptions{}.effective())
+}
+
+// Reads a single OSC packet (either a message or a bundle) from an input
+// stream. The two are distinguished by the "#bundle" string that begins every
+// bundle. As with ReadBundle, the input should contain exactly one packet.
+func ReadPacket(in io.Reader) (OSCPacket, error) {
+	return readPacket(in, 0, DecodeOptions{}.effective())
+}
+
+// Reads a message or bundle, decoding messages with the options. A bundle's
+// timetag may not be earlier than min (the timetag of the enclosing bundle, if
+// any).
+func readPacket(in io.Reader, min OSCTimetag, opts DecodeOptions) (OSCPacket, error) {
+	first, err := readOSCString(in, opts)
+	if err != nil {
+		return nil, annotateError(err, 0, -1, 0)
+	}
+
+	if first == OSC_BUNDLE_TAG {
+		return readBundleBody(in, min, opts)
+	}
+
+	m, err := readMessageBody(in, first, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Reads the remainder of a bundle (timetag and elements), once the "#bundle"
+// string has already been read. The timetag may not be earlier than that of
+// the enclosing bundle (min).
+func readBundleBody(in io.Reader, min OSCTimetag, opts DecodeOptions) (OSCBundle, error) {
+	timetag, err := ReadOSCTimetag(in)
+	if err != nil {
+		return OSCBundle{}, err
+	}
+
+	if timetag < min {
+		return OSCBundle{}, OSCReadErrorf("nested bundle timetag %d is earlier than enclosing bundle timetag %d", timetag, min)
+	}
+
+	bundle := OSCBundle{Timetag: timetag, Elements: []OSCPacket{}}
+
+	for {
+		var size int32
+		if err := binary.Read(in, binary.BigEndian, &size); err == io.EOF {
+			return bundle, nil
+		} else if err != nil {
+			return bundle, OSCReadErrorf("failed to read bundle element size: %w", err)
+		}
+
+		if size <= 0 || size % OSC_BYTE_ALIGNMENT != 0 {
+			return bundle, OSCReadErrorf("invalid bundle element size %d", size)
+		}
+
+		// Each element is read through a limited reader, so that a malformed
+		// element can't read into the next one, and so that a nested bundle
+		// knows where it ends.
+		elemIn := &io.LimitedReader{R: in, N: int64(size)}
+
+		elem, err := readPacket(elemIn, timetag, opts)
+		if err != nil {
+			return bundle, err
+		}
+
+		if elemIn.N != 0 {
+			return bundle, OSCReadErrorf("bundle element declared %d bytes, but only %d were used", size, int64(size) - elemIn.N)
+		}
+
+		bundle.Elements = append(bundle.Elements, elem)
+	}
+}
+
+// Calls f with the packet if it's a message, or with every message in it (in
+// order, including nested bundles) if it's a bundle.
+func eachMessage(packet OSCPacket, f func(Message)) {
+	switch p := packet.(type) {
+	case Message:
+		f(p)
+	case OSCBundle:
+		for _, elem := range p.Elements {
+			eachMessage(elem, f)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gosc
+
+import (
+	"bytes"
+	. "testing"
+)
+
+func TestWriteBundle(t *T) {
+	var out bytes.Buffer
+
+	n, err := WriteBundle(&out, OSCTimetag(1),
+		Message{OSCAddressPattern("/a"), []OSCArg{OSCInt32(5)}})
+	expectNil(t, err)
+	expectSame(t, 8+8+4+12, n)
+	expectSame(t,
+		[]byte{35,98,117,110,100,108,101,0, // "#bundle"
+		       0,0,0,0,0,0,0,1,             // timetag (immediately)
+		       0,0,0,12,                    // element size
+		       47,97,0,0,                   // "/a"
+		       44,105,0,0,                  // ",i"
+		       0,0,0,5},
+		out.Bytes())
+}
+
+func TestBundleRoundTrip(t *T) {
+	var out bytes.Buffer
+
+	bundle := OSCBundle{
+		Timetag: OSCTimetag(100 << 32),
+		Elements: []OSCPacket{
+			Message{OSCAddressPattern("/first"), []OSCArg{OSCString("x"), OSCFloat32(1)}},
+			OSCBundle{
+				Timetag: OSCTimetag(101 << 32),
+				Elements: []OSCPacket{
+					Message{OSCAddressPattern("/nested"), []OSCArg{}},
+				},
+			},
+			Message{OSCAddressPattern("/last"), []OSCArg{OSCBlob([]byte{1,2,3,4})}},
+		},
+	}
+
+	_, err := bundle.WriteTo(&out)
+	expectNil(t, err)
+
+	read, err := ReadBundle(bytes.NewReader(out.Bytes()))
+	expectNil(t, err)
+	expectSame(t, bundle, read)
+
+	packet, err := ReadPacket(bytes.NewReader(out.Bytes()))
+	expectNil(t, err)
+	expectSame(t, bundle, packet)
+}
+
+func TestReadPacketMessage(t *T) {
+	var out bytes.Buffer
+
+	_, err := WriteMessage(&out, OSCAddressPattern("/msg"), OSCInt32(1))
+	expectNil(t, err)
+
+	packet, err := ReadPacket(&out)
+	expectNil(t, err)
+	expectSame(t, Message{OSCAddressPattern("/msg"), []OSCArg{OSCInt32(1)}}, packet)
+}
+
+func TestWriteBundleNestedTimetagOrder(t *T) {
+	var out bytes.Buffer
+
+	_, err := WriteBundle(&out, OSCTimetag(10 << 32), OSCBundle{Timetag: OSCTimetag(9 << 32)})
+	if _, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError, got %#v", err)
+	}
+	expectSame(t, 0, out.Len())
+}
+
+func TestReadBundleErrors(t *T) {
+	header := []byte{35,98,117,110,100,108,101,0, 0,0,0,10,0,0,0,0}
+	message := []byte{47,97,0,0, 44,0,0,0}
+
+	inputs := map[string][]byte{
+		"not a bundle": []byte{47,97,0,0, 0,0,0,0, 0,0,0,0},
+		"negative size": append(append([]byte{}, header...), 0xff,0xff,0xff,0xf8),
+		"unaligned size": append(append(append([]byte{}, header...), 0,0,0,7), message...),
+		"size too large": append(append(append([]byte{}, header...), 0,0,0,12), message...),
+		"size too small": append(append(append([]byte{}, header...), 0,0,0,4), message...),
+		"truncated size": append(append([]byte{}, header...), 0,0),
+		"nested timetag": append(append([]byte{}, header...),
+			0,0,0,16, 35,98,117,110,100,108,101,0, 0,0,0,9,0,0,0,0),
+	}
+
+	for name, input := range inputs {
+		_, err := ReadBundle(bytes.NewReader(input))
+		if _, ok := err.(OSCReadError); !ok {
+			t.Errorf("%s: expected an OSCReadError, got %#v", name, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package gosc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// What to do with a type tag that isn't supported. The size of an unknown
+// argument can't be known, so nothing after it can be decoded either.
+type UnknownTagPolicy int
+
+const (
+	// Fail with an error (of kind ErrUnknownTag).
+	UnknownTagError UnknownTagPolicy = iota
+
+	// Discard the unknown argument and everything after it, keeping the
+	// arguments before it.
+	UnknownTagSkip
+
+	// Keep the unknown argument and everything after it as an OSCRaw
+	// argument.
+	UnknownTagRaw
+)
+
+// DecodeOptions relax the OSC 1.0 rules when reading messages, to accommodate
+// senders that don't follow them. The zero value decodes exactly as
+// ReadMessage does.
+//
+// Options that consume "the rest of the message" read until the end of the
+// input, so they should only be used with inputs holding a single message,
+// such as a datagram, a frame or a bundle element.
+type DecodeOptions struct {
+	// Strict disables all of the other options, so that leniency can be
+	// configured in one place and switched off in another.
+	Strict bool
+
+	// Accept messages with no tag string, as sent by some older
+	// implementations; OSC 1.0 says receivers should tolerate them. Without a
+	// tag string the arguments can't be decoded, so the rest of the message is
+	// returned as a single OSCRaw argument (or no arguments, if there isn't
+	// any data).
+	AllowMissingTagString bool
+
+	// Accept any valid UTF-8 in string arguments, not just ASCII.
+	AllowUTF8Strings bool
+
+	// Accept a final string whose padding is missing, i.e. where the input
+	// ends right after its null terminator.
+	AllowUnpaddedTail bool
+
+	// What to do with unknown type tags.
+	UnknownTagPolicy UnknownTagPolicy
+
+	// The registry of custom argument types to decode. Defaults to
+	// DefaultTypeRegistry. Unlike the other options, this isn't affected by
+	// Strict.
+	Types *TypeRegistry
+
+	// The largest blob to accept, in bytes. Defaults to OSC_MAX_BLOB_SIZE.
+	// Like Types, this isn't affected by Strict.
+	MaxBlobSize int
+}
+
+// Returns the options that are actually in effect.
+func (o DecodeOptions) effective() DecodeOptions {
+	if o.Strict {
+		o = DecodeOptions{Strict: true, Types: o.Types, MaxBlobSize: o.MaxBlobSize}
+	}
+
+	if o.Types == nil {
+		o.Types = DefaultTypeRegistry
+	}
+
+	if o.MaxBlobSize <= 0 {
+		o.MaxBlobSize = OSC_MAX_BLOB_SIZE
+	}
+
+	return o
+}
+
+// ReadMessageWith is like ReadMessage, but with options that relax the rules
+// for decoding.
+func ReadMessageWith(in io.Reader, opts DecodeOptions) (OSCAddressPattern, []OSCArg, error) {
+	m, err := readMessageWith(in, opts.effective())
+	return m.Address, m.Args, err
+}
+
+// ReadPacketWith is like ReadPacket, but decodes messages (including those in
+// bundles) with options that relax the rules.
+func ReadPacketWith(in io.Reader, opts DecodeOptions) (OSCPacket, error) {
+	return readPacket(in, 0, opts.effective())
+}
+
+// Message data that couldn't be decoded, kept as-is. The decoder returns one
+// of these for a message without a tag string, or (with UnknownTagRaw) for
+// the arguments starting at an unknown type tag.
+//
+// Writing an OSCRaw writes its type tags and data back out unchanged, so a
+// message containing one can be forwarded. This is exact for an OSCRaw at the
+// top level of a message with a tag string; one from a message without a tag
+// string has no tags, and so can't be written.
+type OSCRaw struct {
+	// The type tags that weren't decoded, starting with the unknown one.
+	Tags string
+
+	// The undecoded data, up to the end of the message.
+	Data []byte
+}
+
+// Tag returns the first of the raw argument's type tags, or 0 if it has none.
+func (r OSCRaw) Tag() OSCTypeTag {
+	if len(r.Tags) == 0 {
+		return 0
+	}
+
+	return OSCTypeTag(r.Tags[0])
+}
+
+func (r OSCRaw) Valid() error {
+	if len(r.Tags) == 0 {
+		return OSCArgumentErrorf("raw data without type tags can't be written")
+	}
+
+	return nil
+}
+
+func (r OSCRaw) WriteTo(out io.Writer) (int, error) {
+	return out.Write(r.Data)
+}
+
+func (r OSCRaw) String() string {
+	return fmt.Sprintf("raw(%s 0x%x)", r.Tags, r.Data)
+}
+
+// Decodes a message from an input stream, in the same way that parser decodes
+// one from memory. It counts the bytes read, so that errors can report where
+// they occurred.
+type decoder struct {
+	in   io.Reader
+	n    int
+	opts DecodeOptions
+
+	// Set once an unknown tag has ended decoding early.
+	stopped bool
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	n, err := d.in.Read(p)
+	d.n += n
+	return n, err
+}
+
+// Reads the tag string, or (with AllowMissingTagString) handles a message
+// without one. Returns the tag string, or the raw arguments to use instead.
+func (d *decoder) tagString() (string, []OSCArg, error) {
+	if !d.opts.AllowMissingTagString {
+		tags, err := readOSCString(d, d.opts)
+		return string(tags), nil, err
+	}
+
+	var first [1]byte
+	if _, err := io.ReadFull(d, first[:]); err == io.EOF {
+		// No tag string, and no arguments.
+		return "", []OSCArg{}, nil
+	} else if err != nil {
+		return "", nil, OSCReadErrorf("failed to read tag string: %w", err)
+	}
+
+	if first[0] != ',' {
+		data, err := io.ReadAll(d)
+		if err != nil {
+			return "", nil, OSCReadErrorf("failed to read message data: %w", err)
+		}
+		return "", []OSCArg{OSCRaw{Data: append(first[:], data...)}}, nil
+	}
+
+	tags, err := readOSCString(io.MultiReader(bytes.NewReader(first[:]), d), d.opts)
+	return string(tags), nil, err
+}
+
+// Handles an unknown type tag at position i in the tag string, according to
+// the UnknownTagPolicy. Returns the arguments to use in its place.
+func (d *decoder) unknown(tags string, i int) ([]OSCArg, error) {
+	data, err := io.ReadAll(d)
+	if err != nil {
+		return nil, OSCReadErrorf("failed to read message data: %w", err)
+	}
+
+	d.stopped = true
+
+	if d.opts.UnknownTagPolicy == UnknownTagRaw {
+		return []OSCArg{OSCRaw{Tags: tags[i:], Data: data}}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package gosc
+
+import (
+	"bytes"
+	"errors"
+	. "testing"
+)
+
+func readWith(input []byte, opts DecodeOptions) (Message, error) {
+	address, args, err := ReadMessageWith(bytes.NewReader(input), opts)
+	return Message{address, args}, err
+}
+
+func TestDecodeMissingTagString(t *T) {
+	opts := DecodeOptions{AllowMissingTagString: true}
+
+	// Address only.
+	m, err := readWith([]byte{47, 97, 0, 0}, opts)
+	expectNil(t, err)
+	expectSame(t, Message{OSCAddressPattern("/a"), []OSCArg{}}, m)
+
+	// Address and undecodable data.
+	m, err = readWith([]byte{47, 97, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2}, opts)
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCRaw{Data: []byte{0, 0, 0, 1, 0, 0, 0, 2}}}, m.Args)
+
+	// Messages with a tag string are read as usual.
+	data, _ := AppendMessage(nil, OSCAddressPattern("/a"), OSCInt32(1), OSCString("x"))
+	m, err = readWith(data, opts)
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCInt32(1), OSCString("x")}, m.Args)
+
+	// The default is to fail.
+	_, err = readWith([]byte{47, 97, 0, 0}, DecodeOptions{})
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected a missing tag string to be an error by default, got %#v", err)
+	}
+}
+
+func TestDecodeUTF8Strings(t *T) {
+	input := []byte{47, 97, 0, 0, 44, 115, 0, 0, 0xc3, 0xa9, 0, 0}
+
+	_, err := readWith(input, DecodeOptions{})
+	expectArgumentError(t, err, "non-ascii")
+
+	m, err := readWith(input, DecodeOptions{AllowUTF8Strings: true})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCString("é")}, m.Args)
+
+	_, err = readWith([]byte{47, 97, 0, 0, 44, 115, 0, 0, 0xc3, 0, 0, 0}, DecodeOptions{AllowUTF8Strings: true})
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError for invalid UTF-8, got %#v", err)
+	}
+}
+
+func TestDecodeUnpaddedTail(t *T) {
+	input := []byte{47, 97, 0, 0, 44, 105, 115, 0, 0, 0, 0, 1, 97, 98, 0}
+
+	_, err := readWith(input, DecodeOptions{})
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("expected an unpadded string to be an error by default, got %#v", err)
+	}
+
+	m, err := readWith(input, DecodeOptions{AllowUnpaddedTail: true})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCInt32(1), OSCString("ab")}, m.Args)
+}
+
+func TestDecodeUnknownTags(t *T) {
+	input := []byte{47, 97, 0, 0, 44, 105, 122, 105, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 3, 4, 0, 0, 0, 2}
+
+	_, err := readWith(input, DecodeOptions{})
+	if !errors.Is(err, ErrUnknownTag) {
+		t.Errorf("expected ErrUnknownTag, got %#v", err)
+	}
+
+	m, err := readWith(input, DecodeOptions{UnknownTagPolicy: UnknownTagSkip})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCInt32(1)}, m.Args)
+
+	m, err = readWith(input, DecodeOptions{UnknownTagPolicy: UnknownTagRaw})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCInt32(1), OSCRaw{Tags: "zi", Data: []byte{1, 2, 3, 4, 0, 0, 0, 2}}}, m.Args)
+
+	// Raw arguments are written back out unchanged.
+	data, err := m.MarshalBinary()
+	expectNil(t, err)
+	expectSame(t, input, data)
+
+	// Inside an array, decoding stops at the unknown tag.
+	m, err = readWith([]byte{47, 97, 0, 0, 44, 91, 105, 122, 93, 105, 0, 0, 0, 0, 0, 1, 9, 9, 9, 9}, DecodeOptions{UnknownTagPolicy: UnknownTagSkip})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCArray{OSCInt32(1)}}, m.Args)
+}
+
+func TestDecodeStrict(t *T) {
+	opts := DecodeOptions{
+		Strict:                true,
+		AllowMissingTagString: true,
+		AllowUTF8Strings:      true,
+		AllowUnpaddedTail:     true,
+		UnknownTagPolicy:      UnknownTagRaw,
+	}
+
+	_, err := readWith([]byte{47, 97, 0, 0}, opts)
+	if err == nil {
+		t.Errorf("expected Strict to reject a missing tag string")
+	}
+
+	_, err = readWith([]byte{47, 97, 0, 0, 44, 122, 0, 0}, opts)
+	if !errors.Is(err, ErrUnknownTag) {
+		t.Errorf("expected Strict to reject unknown tags, got %#v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package gosc
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Handles a message sent to a matching address. The address is the pattern
+// the message was sent to, which may contain wildcards.
+type HandlerFunc func(address OSCAddressPattern, args []OSCArg)
+
+// Middleware wraps a handler with additional behavior, such as logging or
+// panic recovery.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// A Dispatcher routes incoming messages to handlers. Handlers are registered
+// at concrete addresses, forming a tree of address parts; each incoming
+// address pattern is matched against that tree, and every matching handler is
+// called. Messages that match no handlers go to the fallback handler, if any.
+//
+// A Dispatcher is safe for concurrent use.
+type Dispatcher struct {
+	mu         sync.RWMutex
+	root       *dispatchNode
+	fallback   HandlerFunc
+	middleware []Middleware
+	seq        int
+}
+
+type dispatchNode struct {
+	children map[string]*dispatchNode
+	handlers []dispatchEntry
+}
+
+// Handlers are stored with their registration order, so that a message
+// matching several addresses is handled in a predictable order.
+type dispatchEntry struct {
+	seq     int
+	handler HandlerFunc
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{root: newDispatchNode()}
+}
+
+func newDispatchNode() *dispatchNode {
+	return &dispatchNode{children: make(map[string]*dispatchNode)}
+}
+
+// Handle registers a handler for a concrete address (no wildcards). Any
+// number of handlers can be registered for the same address.
+func (d *Dispatcher) Handle(address OSCAddress, handler HandlerFunc) error {
+	if err := address.Valid(); err != nil {
+		return err
+	}
+
+	if handler == nil {
+		return OSCArgumentErrorf("nil handler for address \"%s\"", address)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	node := d.root
+	for _, name := range strings.Split(string(address[1:]), "/") {
+		child, ok := node.children[name]
+		if !ok {
+			child = newDispatchNode()
+			node.children[name] = child
+		}
+		node = child
+	}
+
+	d.seq++
+	node.handlers = append(node.handlers, dispatchEntry{d.seq, handler})
+	return nil
+}
+
+// HandleFallback sets the handler for messages that don't match any
+// registered address (including messages with malformed address patterns).
+func (d *Dispatcher) HandleFallback(handler HandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.fallback = handler
+}
+
+// Use adds middleware that wraps every handler (including the fallback). The
+// first middleware added is the outermost.
+func (d *Dispatcher) Use(middleware...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.middleware = append(d.middleware, middleware...)
+}
+
+// Dispatch calls every handler whose address matches the address pattern, or
+// the fallback handler if there are none. Returns the number of (non-fallback)
+// handlers that were called.
+func (d *Dispatcher) Dispatch(address OSCAddressPattern, args []OSCArg) int {
+	d.mu.RLock()
+	var entries []dispatchEntry
+	if pattern, err := CompilePattern(string(address)); err == nil {
+		entries = d.root.collect(pattern.parts, 0, entries, make(map[dispatchVisit]bool))
+	}
+	fallback := d.fallback
+	middleware := d.middleware
+	d.mu.RUnlock()
+
+	if len(entries) == 0 {
+		if fallback != nil {
+			wrap(fallback, middleware)(address, args)
+		}
+		return 0
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	for _, entry := range entries {
+		wrap(entry.handler, middleware)(address, args)
+	}
+
+	return len(entries)
+}
+
+// DispatchPacket dispatches a message, or every message in a bundle (including
+// nested bundles), in order. Bundle timetags are ignored; see Scheduler for
+// timed delivery.
+func (d *Dispatcher) DispatchPacket(packet OSCPacket) {
+	switch p := packet.(type) {
+	case Message:
+		d.Dispatch(p.Address, p.Args)
+	case OSCBundle:
+		for _, elem := range p.Elements {
+			d.DispatchPacket(elem)
+		}
+	}
+}
+
+func wrap(handler HandlerFunc, middleware []Middleware) HandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+// A node reached with the pattern parts from index part onwards still to
+// match.
+type dispatchVisit struct {
+	node *dispatchNode
+	part int
+}
+
+// Collects the handlers of every node below this one that matches the pattern
+// parts from index i onwards. Each (node, part) pair is visited at most once,
+// so that "//" wildcards can't make the work grow beyond the size of the tree
+// times the number of parts, and nodes that can be reached more than once are
+// only collected once.
+func (n *dispatchNode) collect(parts []patternPart, i int, entries []dispatchEntry, visited map[dispatchVisit]bool) []dispatchEntry {
+	key := dispatchVisit{n, i}
+	if visited[key] {
+		return entries
+	}
+	visited[key] = true
+
+	if i == len(parts) {
+		return append(entries, n.handlers...)
+	}
+
+	part := parts[i]
+
+	if part.descend {
+		// Either the wildcard matches nothing here, or it matches this child
+		// and possibly more.
+		entries = n.collect(parts, i+1, entries, visited)
+		for _, child := range n.children {
+			entries = child.collect(parts, i, entries, visited)
+		}
+		return entries
+	}
+
+	// Literal parts don't need to be matched against every child.
+	if len(part.tokens) == 1 && part.tokens[0].kind == tokenLiteral {
+		if child, ok := n.children[part.tokens[0].text]; ok {
+			entries = child.collect(parts, i+1, entries, visited)
+		}
+		return entries
+	}
+
+	for name, child := range n.children {
+		if part.match(name) {
+			entries = child.collect(parts, i+1, entries, visited)
+		}
+	}
+
+	return entries
+}
+
+// LoggingMiddleware logs every message before it is handled.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(address OSCAddressPattern, args []OSCArg) {
+			logger.Printf("%s %v", address, args)
+			next(address, args)
+		}
+	}
+}
+
+// RecoverMiddleware recovers from panics in handlers, so that one bad handler
+// doesn't take down the receive loop. The recovered value is passed to
+// onPanic; if onPanic is nil, it is logged with the standard logger instead.
+func RecoverMiddleware(onPanic func(address OSCAddressPattern, args []OSCArg, recovered interface{})) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(address OSCAddressPattern, args []OSCArg) {
+			defer func() {
+				if r := recover(); r != nil {
+					if onPanic != nil {
+						onPanic(address, args, r)
+					} else {
+						log.Printf("panic handling %s: %v", address, r)
+					}
+				}
+			}()
+
+			next(address, args)
+		}
+	}
+}
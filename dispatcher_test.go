@@ -0,0 +1,195 @@
+package gosc
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	. "testing"
+	"time"
+)
+
+// Returns a dispatcher with a recording handler at each address, and the
+// slice the handlers record into.
+func recordingDispatcher(t *T, addresses...string) (*Dispatcher, *[]string) {
+	d := NewDispatcher()
+	var calls []string
+
+	for _, address := range addresses {
+		address := address
+		err := d.Handle(OSCAddress(address), func(OSCAddressPattern, []OSCArg) {
+			calls = append(calls, address)
+		})
+		expectNil(t, err)
+	}
+
+	return d, &calls
+}
+
+func TestDispatcherLiteral(t *T) {
+	d, calls := recordingDispatcher(t, "/mixer/1/mute", "/mixer/2/mute", "/mixer/1/solo")
+
+	expectSame(t, 1, d.Dispatch(OSCAddressPattern("/mixer/1/mute"), nil))
+	expectSame(t, []string{"/mixer/1/mute"}, *calls)
+
+	expectSame(t, 0, d.Dispatch(OSCAddressPattern("/mixer/3/mute"), nil))
+	expectSame(t, 0, d.Dispatch(OSCAddressPattern("/mixer/1"), nil))
+	expectSame(t, 1, len(*calls))
+}
+
+func TestDispatcherWildcards(t *T) {
+	d, calls := recordingDispatcher(t, "/mixer/1/mute", "/mixer/2/mute", "/mixer/1/solo", "/mute")
+
+	expectSame(t, 2, d.Dispatch(OSCAddressPattern("/mixer/*/mute"), nil))
+	expectSame(t, []string{"/mixer/1/mute", "/mixer/2/mute"}, *calls)
+
+	*calls = nil
+	expectSame(t, 2, d.Dispatch(OSCAddressPattern("/mixer/1/{mute,solo}"), nil))
+	expectSame(t, []string{"/mixer/1/mute", "/mixer/1/solo"}, *calls)
+
+	*calls = nil
+	expectSame(t, 3, d.Dispatch(OSCAddressPattern("//mute"), nil))
+	expectSame(t, []string{"/mixer/1/mute", "/mixer/2/mute", "/mute"}, *calls)
+}
+
+func TestDispatcherPathTraversalVisitsOnce(t *T) {
+	d, calls := recordingDispatcher(t, "/x/x/y")
+
+	expectSame(t, 1, d.Dispatch(OSCAddressPattern("//x//y"), nil))
+	expectSame(t, []string{"/x/x/y"}, *calls)
+}
+
+func TestDispatcherManyPathTraversals(t *T) {
+	// A chain of 30 nodes, each with a handler.
+	var addresses []string
+	for i := 1; i <= 30; i++ {
+		addresses = append(addresses, strings.Repeat("/a", i))
+	}
+	d, calls := recordingDispatcher(t, addresses...)
+
+	start := time.Now()
+	// Fifteen parts match only the sixteen deepest nodes.
+	expectSame(t, 16, d.Dispatch(OSCAddressPattern(strings.Repeat("//*", 15)), nil))
+	expectSame(t, 0, d.Dispatch(OSCAddressPattern(strings.Repeat("//a", 15) + "//b"), nil))
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("dispatching took %s", elapsed)
+	}
+
+	expectSame(t, addresses[14:], *calls)
+}
+
+func TestDispatcherArgsAndMultipleHandlers(t *T) {
+	d := NewDispatcher()
+	var got []OSCArg
+	count := 0
+
+	d.Handle(OSCAddress("/a"), func(address OSCAddressPattern, args []OSCArg) {
+		expectSame(t, OSCAddressPattern("/?"), address)
+		got = args
+		count++
+	})
+	d.Handle(OSCAddress("/a"), func(OSCAddressPattern, []OSCArg) { count++ })
+
+	expectSame(t, 2, d.Dispatch(OSCAddressPattern("/?"), []OSCArg{OSCInt32(1)}))
+	expectSame(t, []OSCArg{OSCInt32(1)}, got)
+	expectSame(t, 2, count)
+}
+
+func TestDispatcherHandleInvalid(t *T) {
+	d := NewDispatcher()
+
+	if _, ok := d.Handle(OSCAddress("/a/*"), func(OSCAddressPattern, []OSCArg) {}).(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for a wildcard address")
+	}
+
+	if _, ok := d.Handle(OSCAddress("/a"), nil).(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for a nil handler")
+	}
+}
+
+func TestDispatcherFallback(t *T) {
+	d, calls := recordingDispatcher(t, "/a")
+
+	var fallback []OSCAddressPattern
+	d.HandleFallback(func(address OSCAddressPattern, args []OSCArg) {
+		fallback = append(fallback, address)
+	})
+
+	d.Dispatch(OSCAddressPattern("/a"), nil)
+	d.Dispatch(OSCAddressPattern("/b"), nil)
+	d.Dispatch(OSCAddressPattern("/[b"), nil)
+
+	expectSame(t, []string{"/a"}, *calls)
+	expectSame(t, []OSCAddressPattern{"/b", "/[b"}, fallback)
+}
+
+func TestDispatcherDispatchPacket(t *T) {
+	d, calls := recordingDispatcher(t, "/a", "/b")
+
+	d.DispatchPacket(OSCBundle{
+		Timetag: OSC_TIMETAG_IMMEDIATELY,
+		Elements: []OSCPacket{
+			Message{OSCAddressPattern("/b"), nil},
+			OSCBundle{Timetag: OSC_TIMETAG_IMMEDIATELY, Elements: []OSCPacket{Message{OSCAddressPattern("/a"), nil}}},
+		},
+	})
+
+	expectSame(t, []string{"/b", "/a"}, *calls)
+}
+
+func TestDispatcherMiddleware(t *T) {
+	d := NewDispatcher()
+	var order []string
+
+	d.Use(func(next HandlerFunc) HandlerFunc {
+		return func(address OSCAddressPattern, args []OSCArg) {
+			order = append(order, "outer")
+			next(address, args)
+		}
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(address OSCAddressPattern, args []OSCArg) {
+			order = append(order, "inner")
+			next(address, args)
+		}
+	})
+
+	d.Handle(OSCAddress("/a"), func(OSCAddressPattern, []OSCArg) {
+		order = append(order, "handler")
+	})
+
+	d.Dispatch(OSCAddressPattern("/a"), nil)
+	expectSame(t, []string{"outer", "inner", "handler"}, order)
+}
+
+func TestRecoverMiddleware(t *T) {
+	d, calls := recordingDispatcher(t)
+
+	var recovered interface{}
+	d.Use(RecoverMiddleware(func(address OSCAddressPattern, args []OSCArg, r interface{}) {
+		recovered = r
+	}))
+
+	d.Handle(OSCAddress("/bad"), func(OSCAddressPattern, []OSCArg) {
+		panic("oops")
+	})
+	d.Handle(OSCAddress("/bad"), func(OSCAddressPattern, []OSCArg) {
+		*calls = append(*calls, "/bad")
+	})
+
+	expectSame(t, 2, d.Dispatch(OSCAddressPattern("/bad"), nil))
+	expectSame(t, "oops", recovered)
+	expectSame(t, []string{"/bad"}, *calls)
+}
+
+func TestLoggingMiddleware(t *T) {
+	var out bytes.Buffer
+	d := NewDispatcher()
+
+	d.Use(LoggingMiddleware(log.New(&out, "", 0)))
+	d.Handle(OSCAddress("/a"), func(OSCAddressPattern, []OSCArg) {})
+	d.Dispatch(OSCAddressPattern("/a"), []OSCArg{OSCInt32(42)})
+
+	if !strings.Contains(out.String(), "/a [42]") {
+		t.Errorf("expected log output to contain the address and arguments, got %q", out.String())
+	}
+}
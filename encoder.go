@@ -0,0 +1,253 @@
+package gosc
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// AppendMessage appends an encoded OSC message to dst and returns the extended
+// buffer. The encoding is identical to WriteMessage's, but the built-in
+// argument types are encoded directly into the buffer, so nothing is
+// allocated unless dst has to grow. Reusing the returned buffer (e.g.
+// buf = AppendMessage(buf[:0], ...)) makes encoding allocation-free.
+//
+// If the message is invalid, dst is returned unchanged along with the error.
+func AppendMessage(dst []byte, address OSCAddressPattern, args...OSCArg) ([]byte, error) {
+	if err := address.Valid(); err != nil {
+		return dst, err
+	}
+
+	for _, arg := range args {
+		if arg == nil {
+			return dst, OSCArgumentErrorf("nil argument")
+		}
+
+		if err := arg.Valid(); err != nil {
+			return dst, err
+		}
+	}
+
+	start := len(dst)
+	dst = appendPaddedString(dst, string(address))
+
+	tags := len(dst)
+	dst = append(dst, ',')
+	for _, arg := range args {
+		dst = appendTypeTags(dst, arg)
+	}
+	dst = append(dst, 0)
+	dst = appendPadding(dst, len(dst) - tags)
+
+	for _, arg := range args {
+		var err error
+		if dst, err = appendArg(dst, arg); err != nil {
+			return dst[:start], err
+		}
+	}
+
+	return dst, nil
+}
+
+// Appends the encoding of a single (already validated) argument.
+func appendArg(dst []byte, arg OSCArg) ([]byte, error) {
+	switch a := arg.(type) {
+	case OSCInt32:
+		return appendUint32(dst, uint32(a)), nil
+	case OSCFloat32:
+		return appendUint32(dst, math.Float32bits(float32(a))), nil
+	case OSCString:
+		return appendPaddedString(dst, string(a)), nil
+	case OSCStringAlt:
+		return appendPaddedString(dst, string(a)), nil
+	case OSCBlob:
+		dst = appendUint32(dst, uint32(len(a)))
+		dst = append(dst, a...)
+		return appendPadding(dst, len(a)), nil
+	case OSCInt64:
+		return appendUint64(dst, uint64(a)), nil
+	case OSCFloat64:
+		return appendUint64(dst, math.Float64bits(float64(a))), nil
+	case OSCTimetag:
+		return appendUint64(dst, uint64(a)), nil
+	case OSCChar:
+		return append(dst, 0, 0, 0, byte(a)), nil
+	case OSCRGBA:
+		return append(dst, a.R, a.G, a.B, a.A), nil
+	case OSCMIDI:
+		return append(dst, a.Port, a.Status, a.Data1, a.Data2), nil
+	case OSCBool, OSCNil, OSCInfinity:
+		return dst, nil
+	case OSCArray:
+		for _, elem := range a {
+			var err error
+			if dst, err = appendArg(dst, elem); err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	default:
+		// Types from outside the package can only be written through their
+		// WriteTo method.
+		w := appendWriter{dst}
+		_, err := arg.WriteTo(&w)
+		return w.buf, err
+	}
+}
+
+// Adapts an append-style buffer to io.Writer.
+type appendWriter struct {
+	buf []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+func appendUint32(dst []byte, v uint32) []byte {
+	n := len(dst)
+	dst = append(dst, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(dst[n:], v)
+	return dst
+}
+
+func appendUint64(dst []byte, v uint64) []byte {
+	n := len(dst)
+	dst = append(dst, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(dst[n:], v)
+	return dst
+}
+
+// Appends a null-terminated, null-padded OSC-string.
+func appendPaddedString(dst []byte, s string) []byte {
+	dst = append(dst, s...)
+	return appendPadding(append(dst, 0), len(s) + 1)
+}
+
+// Appends enough zero bytes to pad n bytes of data to a multiple of four.
+func appendPadding(dst []byte, n int) []byte {
+	for ; n % OSC_BYTE_ALIGNMENT != 0; n++ {
+		dst = append(dst, 0)
+	}
+
+	return dst
+}
+
+// An Encoder encodes messages into a buffer that is reused from one message to
+// the next, so that encoding doesn't allocate once the buffer has grown to
+// the size of the largest message. Messages can be encoded from a list of
+// arguments (Encode), or built up one argument at a time with typed methods
+// that don't box their values into OSCArgs, e.g.:
+//
+//	e.Begin("/imu/3")
+//	e.Float32(x)
+//	e.Float32(y)
+//	e.Float32(z)
+//	data, err := e.Bytes()
+//
+// The returned data is only valid until the next call to Encode or Begin. An
+// Encoder is not safe for concurrent use.
+type Encoder struct {
+	buf  []byte
+	tags []byte
+	data []byte
+
+	address OSCAddressPattern
+	err     error
+}
+
+// Encode encodes a complete message, returning the encoded bytes.
+func (e *Encoder) Encode(address OSCAddressPattern, args...OSCArg) ([]byte, error) {
+	var err error
+	e.buf, err = AppendMessage(e.buf[:0], address, args...)
+	return e.buf, err
+}
+
+// Begin starts building a new message.
+func (e *Encoder) Begin(address OSCAddressPattern) {
+	e.address = address
+	e.tags = append(e.tags[:0], ',')
+	e.data = e.data[:0]
+	e.err = address.Valid()
+}
+
+func (e *Encoder) Int32(v int32) {
+	e.tags = append(e.tags, byte(OSC_TYPE_INT32))
+	e.data = appendUint32(e.data, uint32(v))
+}
+
+func (e *Encoder) Float32(v float32) {
+	e.tags = append(e.tags, byte(OSC_TYPE_FLOAT32))
+	e.data = appendUint32(e.data, math.Float32bits(v))
+}
+
+func (e *Encoder) Int64(v int64) {
+	e.tags = append(e.tags, byte(OSC_ETYPE_INT64))
+	e.data = appendUint64(e.data, uint64(v))
+}
+
+func (e *Encoder) Float64(v float64) {
+	e.tags = append(e.tags, byte(OSC_ETYPE_FLOAT64))
+	e.data = appendUint64(e.data, math.Float64bits(v))
+}
+
+func (e *Encoder) String(s string) {
+	if e.err == nil {
+		e.err = OSCString(s).Valid()
+	}
+
+	e.tags = append(e.tags, byte(OSC_TYPE_STRING))
+	e.data = appendPaddedString(e.data, s)
+}
+
+func (e *Encoder) Blob(b []byte) {
+	e.tags = append(e.tags, byte(OSC_TYPE_BLOB))
+	e.data = appendUint32(e.data, uint32(len(b)))
+	e.data = appendPadding(append(e.data, b...), len(b))
+}
+
+func (e *Encoder) Bool(b bool) {
+	e.tags = append(e.tags, byte(OSCBool(b).Tag()))
+}
+
+func (e *Encoder) Nil() {
+	e.tags = append(e.tags, byte(OSC_ETYPE_NIL))
+}
+
+// Arg adds an argument of any type.
+func (e *Encoder) Arg(arg OSCArg) {
+	if arg == nil {
+		if e.err == nil {
+			e.err = OSCArgumentErrorf("nil argument")
+		}
+		return
+	}
+
+	if err := arg.Valid(); err != nil {
+		if e.err == nil {
+			e.err = err
+		}
+		return
+	}
+
+	e.tags = appendTypeTags(e.tags, arg)
+
+	var err error
+	if e.data, err = appendArg(e.data, arg); err != nil && e.err == nil {
+		e.err = err
+	}
+}
+
+// Bytes returns the encoded message built since Begin, or the first error
+// encountered while building it.
+func (e *Encoder) Bytes() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	buf := appendPaddedString(e.buf[:0], string(e.address))
+	buf = appendPadding(append(append(buf, e.tags...), 0), len(e.tags) + 1)
+	e.buf = append(buf, e.data...)
+
+	return e.buf, nil
+}
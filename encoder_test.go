@@ -0,0 +1,167 @@
+package gosc
+
+import (
+	"bytes"
+	. "testing"
+)
+
+var encoderTestArgs = []OSCArg{
+	OSCInt32(1337),
+	OSCFloat32(13.37),
+	OSCString("foo"),
+	OSCBlob([]byte{1,2,3,4,5}),
+	OSCInt64(-1),
+	OSCFloat64(0.5),
+	OSCTimetag(1),
+	OSCStringAlt("sym"),
+	OSCChar('c'),
+	OSCRGBA{1, 2, 3, 4},
+	OSCMIDI{0, 0x90, 60, 127},
+	OSCBool(true),
+	OSCNil{},
+	OSCInfinity{},
+	OSCArray{OSCInt32(1), OSCArray{OSCString("x")}},
+}
+
+func TestAppendMessageMatchesWriteMessage(t *T) {
+	var out bytes.Buffer
+
+	_, err := WriteMessage(&out, OSCAddressPattern("/send/this/here"), encoderTestArgs...)
+	expectNil(t, err)
+
+	prefix := []byte{9, 9}
+	data, err := AppendMessage(prefix, OSCAddressPattern("/send/this/here"), encoderTestArgs...)
+	expectNil(t, err)
+	expectSame(t, prefix, data[:2])
+	expectSame(t, out.Bytes(), data[2:])
+}
+
+func TestAppendMessageInvalid(t *T) {
+	dst := []byte{1, 2, 3}
+
+	data, err := AppendMessage(dst, OSCAddressPattern("/a"), OSCString("tɘst"))
+	if _, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError, got %#v", err)
+	}
+	expectSame(t, dst, data)
+
+	_, err = AppendMessage(nil, OSCAddressPattern("a"))
+	if _, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError, got %#v", err)
+	}
+}
+
+func TestEncoder(t *T) {
+	var e Encoder
+	var out bytes.Buffer
+
+	WriteMessage(&out, OSCAddressPattern("/imu"),
+		OSCFloat32(1), OSCFloat32(2), OSCInt32(3), OSCInt64(4), OSCFloat64(5),
+		OSCString("six"), OSCBlob([]byte{7}), OSCBool(false), OSCNil{}, OSCArray{OSCInt32(8)})
+
+	e.Begin(OSCAddressPattern("/imu"))
+	e.Float32(1)
+	e.Float32(2)
+	e.Int32(3)
+	e.Int64(4)
+	e.Float64(5)
+	e.String("six")
+	e.Blob([]byte{7})
+	e.Bool(false)
+	e.Nil()
+	e.Arg(OSCArray{OSCInt32(8)})
+
+	data, err := e.Bytes()
+	expectNil(t, err)
+	expectSame(t, out.Bytes(), data)
+
+	data, err = e.Encode(OSCAddressPattern("/imu"), OSCInt32(1))
+	expectNil(t, err)
+	expectSame(t, []byte{47,105,109,117,0,0,0,0, 44,105,0,0, 0,0,0,1}, data)
+
+	e.Begin(OSCAddressPattern("/imu"))
+	e.String("tɘst")
+	e.Int32(1)
+	if _, err := e.Bytes(); err == nil {
+		t.Errorf("expected an error for an invalid string")
+	}
+}
+
+func TestEncoderAllocations(t *T) {
+	var e Encoder
+	args := []OSCArg{OSCFloat32(1), OSCFloat32(2), OSCFloat32(3), OSCInt32(4), OSCString("imu")}
+
+	allocs := AllocsPerRun(100, func() {
+		e.Encode(OSCAddressPattern("/sensor/12/imu"), args...)
+	})
+	expectSame(t, 0.0, allocs)
+
+	allocs = AllocsPerRun(100, func() {
+		e.Begin(OSCAddressPattern("/sensor/12/imu"))
+		e.Float32(1)
+		e.Float32(2)
+		e.Float32(3)
+		e.Int32(4)
+		e.Bytes()
+	})
+	expectSame(t, 0.0, allocs)
+
+	buf := make([]byte, 0, 256)
+	allocs = AllocsPerRun(100, func() {
+		buf, _ = AppendMessage(buf[:0], OSCAddressPattern("/sensor/12/imu"), args...)
+	})
+	expectSame(t, 0.0, allocs)
+
+	// Wildcards are checked without compiling the pattern.
+	for _, address := range []OSCAddressPattern{"/sensor/*/imu", "/sensor/{a,b}/imu", "/sensor/[0-9]?//imu"} {
+		allocs = AllocsPerRun(100, func() {
+			buf, _ = AppendMessage(buf[:0], address, args...)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected no allocations, got %v", address, allocs)
+		}
+	}
+}
+
+var benchmarkArgs = []OSCArg{
+	OSCFloat32(0.1), OSCFloat32(0.2), OSCFloat32(0.3),
+	OSCFloat32(0.4), OSCFloat32(0.5), OSCFloat32(0.6),
+	OSCInt32(123456),
+}
+
+func BenchmarkWriteMessage(b *B) {
+	var out bytes.Buffer
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		out.Reset()
+		WriteMessage(&out, OSCAddressPattern("/sensor/12/imu"), benchmarkArgs...)
+	}
+}
+
+func BenchmarkAppendMessage(b *B) {
+	buf := make([]byte, 0, 256)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf, _ = AppendMessage(buf[:0], OSCAddressPattern("/sensor/12/imu"), benchmarkArgs...)
+	}
+}
+
+func BenchmarkEncoderTyped(b *B) {
+	var e Encoder
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		v := float32(i)
+		e.Begin(OSCAddressPattern("/sensor/12/imu"))
+		e.Float32(v)
+		e.Float32(v)
+		e.Float32(v)
+		e.Float32(v)
+		e.Float32(v)
+		e.Float32(v)
+		e.Int32(int32(i))
+		e.Bytes()
+	}
+}
@@ -1,25 +1,206 @@
 package gosc
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 )
 
-type OSCArgumentError string
+// Sentinel errors describing the kind of problem behind an OSCReadError or
+// OSCArgumentError, for use with errors.Is:
+//
+//	if errors.Is(err, gosc.ErrTruncated) { ... }
+var (
+	// The input ended in the middle of a packet.
+	ErrTruncated = errors.New("unexpected end of input")
 
+	// A string or blob was followed by non-zero padding, or wasn't padded to
+	// a multiple of four bytes.
+	ErrBadPadding = errors.New("bad padding")
+
+	// The tag string contained a type tag that isn't supported.
+	ErrUnknownTag = errors.New("unknown type tag")
+
+	// An address or address pattern was malformed.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// A blob or frame size was larger than the configured limit.
+	ErrTooLarge = errors.New("size exceeds limit")
+)
+
+// An error in the arguments (or address) of a message being built or sent.
+type OSCArgumentError struct {
+	Msg string
+
+	// The argument the error applies to, or -1.
+	Index int
+
+	// The type tag of the offending argument, or 0.
+	Tag OSCTypeTag
+
+	// One of the sentinel errors above, or nil.
+	Kind error
+
+	// The underlying error, or nil.
+	Err error
+}
+
+// Creates an OSCArgumentError with a formatted message. An error argument
+// formatted with %w becomes the underlying error, as with fmt.Errorf.
 func OSCArgumentErrorf(f string, args...interface{}) OSCArgumentError {
-	return OSCArgumentError(fmt.Sprintf(f, args...))
+	msg, err, kind := formatError(f, args)
+	return OSCArgumentError{Msg: msg, Index: -1, Kind: kind, Err: err}
 }
 
 func (e OSCArgumentError) Error() string {
-	return string(e)
+	return errorContext(-1, e.Index, e.Tag) + e.Msg
+}
+
+func (e OSCArgumentError) Unwrap() []error {
+	return unwrapErrors(e.Kind, e.Err)
+}
+
+// Returns a copy of the error with the specified kind.
+func (e OSCArgumentError) withKind(kind error) OSCArgumentError {
+	e.Kind = kind
+	return e
 }
 
-type OSCReadError string
+// An error in a packet being read or parsed.
+type OSCReadError struct {
+	Msg string
+
+	// The byte offset of the field being decoded, from the start of the
+	// message, or -1 if unknown.
+	Offset int
+
+	// The argument being decoded, or -1.
+	Index int
+
+	// The type tag of the argument being decoded, or 0.
+	Tag OSCTypeTag
 
+	// One of the sentinel errors above, or nil.
+	Kind error
+
+	// The underlying error (e.g. an I/O error), or nil.
+	Err error
+}
+
+// Creates an OSCReadError with a formatted message. An error argument
+// formatted with %w becomes the underlying error, as with fmt.Errorf; if it is
+// itself an OSC error, or io.EOF or io.ErrUnexpectedEOF, the kind is taken
+// from it.
 func OSCReadErrorf(f string, args...interface{}) OSCReadError {
-	return OSCReadError(fmt.Sprintf(f, args...))
+	msg, err, kind := formatError(f, args)
+	return OSCReadError{Msg: msg, Offset: -1, Index: -1, Kind: kind, Err: err}
 }
 
 func (e OSCReadError) Error() string {
-	return string(e)
+	return errorContext(e.Offset, e.Index, e.Tag) + e.Msg
+}
+
+func (e OSCReadError) Unwrap() []error {
+	return unwrapErrors(e.Kind, e.Err)
+}
+
+// Returns a copy of the error with the specified kind.
+func (e OSCReadError) withKind(kind error) OSCReadError {
+	e.Kind = kind
+	return e
+}
+
+func formatError(f string, args []interface{}) (string, error, error) {
+	formatted := fmt.Errorf(f, args...)
+	err := errors.Unwrap(formatted)
+
+	var kind error
+	switch e := err.(type) {
+	case OSCReadError:
+		kind = e.Kind
+	case OSCArgumentError:
+		kind = e.Kind
+	case nil:
+	default:
+		if e == io.EOF || e == io.ErrUnexpectedEOF {
+			kind = ErrTruncated
+		}
+	}
+
+	return formatted.Error(), err, kind
+}
+
+// Describes where an error occurred, e.g. "argument 2 ('f') at byte 16: ".
+func errorContext(offset, index int, tag OSCTypeTag) string {
+	var context []string
+
+	if index >= 0 {
+		context = append(context, fmt.Sprintf("argument %d", index))
+	}
+
+	if tag != 0 {
+		context = append(context, fmt.Sprintf("('%c')", tag))
+	}
+
+	if offset >= 0 {
+		context = append(context, fmt.Sprintf("at byte %d", offset))
+	}
+
+	if len(context) == 0 {
+		return ""
+	}
+
+	return strings.Join(context, " ") + ": "
+}
+
+func unwrapErrors(kind, err error) []error {
+	var errs []error
+
+	if kind != nil {
+		errs = append(errs, kind)
+	}
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// Fills in the position of an OSC error, where it isn't already known. Pass
+// -1 or 0 to leave a field alone. Other errors are returned unchanged.
+func annotateError(err error, offset, index int, tag OSCTypeTag) error {
+	switch e := err.(type) {
+	case OSCReadError:
+		if e.Offset < 0 {
+			e.Offset = offset
+		}
+		if e.Index < 0 {
+			e.Index = index
+		}
+		if e.Tag == 0 {
+			e.Tag = tag
+		}
+		return e
+	case OSCArgumentError:
+		if e.Index < 0 {
+			e.Index = index
+		}
+		if e.Tag == 0 {
+			e.Tag = tag
+		}
+		return e
+	}
+
+	return err
+}
+
+// Marks an OSCArgumentError from validating an address as ErrInvalidAddress.
+func invalidAddress(err error) error {
+	if e, ok := err.(OSCArgumentError); ok && e.Kind == nil {
+		return e.withKind(ErrInvalidAddress)
+	}
+
+	return err
 }
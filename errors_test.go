@@ -0,0 +1,133 @@
+package gosc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	. "testing"
+	"testing/iotest"
+)
+
+// Decodes the input with both ReadMessage and ParseMessage, which should
+// report the same errors.
+func decodeErrors(input []byte) []error {
+	_, _, readErr := ReadMessage(bytes.NewReader(input))
+	_, parseErr := ParseMessage(input)
+	return []error{readErr, parseErr}
+}
+
+func TestErrorKinds(t *T) {
+	inputs := []struct {
+		name  string
+		input []byte
+		kind  error
+	}{
+		{"truncated address", []byte{47, 97}, ErrTruncated},
+		{"truncated address padding", []byte{47, 97, 0}, ErrTruncated},
+		{"truncated int32", []byte{47, 97, 0, 0, 44, 105, 0, 0, 0, 0}, ErrTruncated},
+		{"truncated blob", []byte{47, 97, 0, 0, 44, 98, 0, 0, 0, 0, 0, 8, 1, 2}, ErrTruncated},
+		{"bad string padding", []byte{47, 97, 0, 1, 44, 0, 0, 0}, ErrBadPadding},
+		{"bad blob padding", []byte{47, 97, 0, 0, 44, 98, 0, 0, 0, 0, 0, 1, 1, 1, 0, 0}, ErrBadPadding},
+		{"unknown tag", []byte{47, 97, 0, 0, 44, 122, 0, 0}, ErrUnknownTag},
+		{"invalid address", []byte{97, 0, 0, 0, 44, 0, 0, 0}, ErrInvalidAddress},
+		{"invalid pattern", []byte{47, 91, 0, 0, 44, 0, 0, 0}, ErrInvalidAddress},
+	}
+
+	kinds := []error{ErrTruncated, ErrBadPadding, ErrUnknownTag, ErrInvalidAddress, ErrTooLarge}
+
+	for _, in := range inputs {
+		for _, err := range decodeErrors(in.input) {
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == in.kind) {
+					t.Errorf("%s: expected errors.Is(%q, %q) to be %v", in.name, err, kind, kind == in.kind)
+				}
+			}
+		}
+	}
+}
+
+func TestErrorPosition(t *T) {
+	data, _ := AppendMessage(nil, OSCAddressPattern("/a"), OSCInt32(1), OSCArray{OSCFloat32(2), OSCString("x")})
+
+	// Truncate in the middle of the string inside the array.
+	for _, err := range decodeErrors(data[:len(data)-2]) {
+		var readErr OSCReadError
+		if !errors.As(err, &readErr) {
+			t.Fatalf("expected an OSCReadError, got %#v", err)
+		}
+
+		expectSame(t, 20, readErr.Offset)
+		expectSame(t, 1, readErr.Index)
+		expectSame(t, OSC_TYPE_STRING, readErr.Tag)
+		expectSame(t, ErrTruncated, readErr.Kind)
+
+		if !strings.HasPrefix(err.Error(), "argument 1 ('s') at byte 20: ") {
+			t.Errorf("expected the error to start with its position, got %q", err)
+		}
+	}
+
+	for _, err := range decodeErrors([]byte{47, 97, 0, 0, 44, 105, 122, 0, 0, 0, 0, 1}) {
+		readErr := err.(OSCReadError)
+		expectSame(t, 12, readErr.Offset)
+		expectSame(t, 1, readErr.Index)
+		expectSame(t, OSCTypeTag('z'), readErr.Tag)
+	}
+
+	for _, err := range decodeErrors([]byte{47, 97, 0, 0, 105, 0, 0, 0}) {
+		expectSame(t, 4, err.(OSCReadError).Offset)
+	}
+}
+
+func TestErrorCause(t *T) {
+	cause := errors.New("connection reset")
+	_, _, err := ReadMessage(io.MultiReader(bytes.NewReader([]byte{47, 97, 0, 0, 44, 105, 0, 0}), iotest.ErrReader(cause)))
+
+	if !errors.Is(err, cause) {
+		t.Errorf("expected the error to wrap the I/O error, got %#v", err)
+	}
+
+	if errors.Is(err, ErrTruncated) {
+		t.Errorf("expected an I/O error not to be reported as truncation")
+	}
+
+	expectSame(t, 8, err.(OSCReadError).Offset)
+}
+
+func TestErrorTooLarge(t *T) {
+	_, err := ReadOSCBlobLimit(bytes.NewReader([]byte{0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0}), 4)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for a blob over the limit, got %#v", err)
+	}
+
+	var frame bytes.Buffer
+	WriteFramedMessage(&frame, OSCAddressPattern("/a"), OSCBlob(make([]byte, OSC_MAX_FRAME_SIZE)))
+	_, _, err = ReadFramedMessage(&frame)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for a frame over the limit, got %#v", err)
+	}
+}
+
+func TestArgumentErrorKinds(t *T) {
+	var buf bytes.Buffer
+
+	_, err := WriteMessage(&buf, OSCAddressPattern("mixer"))
+	if !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("expected ErrInvalidAddress, got %#v", err)
+	}
+
+	err = OSCAddress("/a/*").Valid()
+	if !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("expected ErrInvalidAddress, got %#v", err)
+	}
+
+	// Wrapping keeps the kind.
+	_, err = Marshal(OSCAddressPattern("/a"), struct{ A OSCArg }{OSCString("\xff")})
+	expectArgumentError(t, err, "non-ascii")
+
+	_, err = ValuesToArgs(1, struct{}{})
+	var argErr OSCArgumentError
+	if !errors.As(err, &argErr) || !strings.HasPrefix(argErr.Error(), "value 1: ") {
+		t.Errorf("expected an OSCArgumentError, got %#v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/tokenshift/gosc"
+)
+
+const dumpUsage = `usage: goscli dump [options] <listen>
+
+Listens for OSC packets and prints each message received, with the time it
+arrived, where it came from, its address, tag string and arguments. The
+listen address is a URL as for send; a bare ":port" listens on UDP, e.g.
+
+  goscli dump :9000
+  goscli dump -format json -address '/mixer/*/fader' tcp://:9000
+  goscli dump -format hex -source 192.168.1.20 :9000
+
+Messages in bundles are printed one by one, with the bundle's timetag.
+Packets that can't be decoded are always printed, with the error.
+
+Formats:
+  text  one line per message (the default)
+  json  one JSON object per message (JSON Lines)
+  hex   a hex dump of each packet, with byte offsets, then its messages
+
+Options:
+`
+
+func runDump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, dumpUsage)
+		flags.PrintDefaults()
+	}
+
+	var addresses, sources stringList
+	format := flags.String("format", "text", "output format: text, json or hex")
+	flags.Var(&addresses, "address", "only print messages matching this address pattern (repeatable)")
+	flags.Var(&sources, "source", "only print packets from this host or host:port (repeatable)")
+	slip := flags.Bool("slip", false, "expect stream transports to be framed with SLIP (OSC 1.1) instead of a size prefix")
+	lenient := flags.Bool("lenient", false, "accept messages without a tag string, non-ASCII strings and unknown types")
+
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return usageErrorf("expected an address to listen on")
+	}
+
+	d := &dumper{sources: sources}
+
+	switch *format {
+	case "text", "json", "hex":
+		d.format = *format
+	default:
+		return usageErrorf("unknown format \"%s\"", *format)
+	}
+
+	for _, a := range addresses {
+		p, err := gosc.CompilePattern(a)
+		if err != nil {
+			return usageErrorf("invalid address pattern \"%s\": %s", a, err)
+		}
+		d.patterns = append(d.patterns, p)
+	}
+
+	if *lenient {
+		d.decode = gosc.DecodeOptions{
+			AllowMissingTagString: true,
+			AllowUTF8Strings:      true,
+			AllowUnpaddedTail:     true,
+			UnknownTagPolicy:      gosc.UnknownTagRaw,
+		}
+	} else {
+		d.decode = gosc.DecodeOptions{Strict: true}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	d.out = out
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return listen(ctx, flags.Arg(0), *slip, d.packet, d.error)
+}
+
+// A flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// Matches a source address against filters, which are either a host or an
+// exact host:port.
+func matchSource(sources []string, addr string) bool {
+	if len(sources) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	for _, s := range sources {
+		if s == addr || s == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Prints the packets received by dump.
+type dumper struct {
+	out      *bufio.Writer
+	format   string
+	patterns []*gosc.Pattern
+	sources  []string
+	decode   gosc.DecodeOptions
+}
+
+// A message received in a packet, along with the timetag of the bundle it came
+// in (if any).
+type dumpedMessage struct {
+	msg     gosc.Message
+	bundled bool
+	timetag gosc.OSCTimetag
+}
+
+func (d *dumper) packet(data []byte, from net.Addr) {
+	if !matchSource(d.sources, from.String()) {
+		return
+	}
+
+	now := time.Now()
+	msgs, err := d.decodePacket(data)
+
+	// Filtered messages are dropped, but errors are always shown.
+	if len(d.patterns) > 0 {
+		var matched []dumpedMessage
+		for _, m := range msgs {
+			if d.matchAddress(m.msg.Address) {
+				matched = append(matched, m)
+			}
+		}
+		if len(matched) == 0 && err == nil {
+			return
+		}
+		msgs = matched
+	}
+
+	switch d.format {
+	case "text":
+		for _, m := range msgs {
+			d.printText(now, from, m)
+		}
+	case "json":
+		for _, m := range msgs {
+			d.printJSON(now, from, &m, nil)
+		}
+	case "hex":
+		fmt.Fprintf(d.out, "%s %s %d bytes\n", now.Format(time.RFC3339Nano), from, len(data))
+		d.out.WriteString(hex.Dump(data))
+		for _, m := range msgs {
+			fmt.Fprintf(d.out, "  %s\n", m)
+		}
+	}
+
+	if err != nil && d.format == "hex" {
+		fmt.Fprintf(d.out, "  error: %s\n", err)
+	} else if err != nil {
+		d.printError(now, from, err)
+	}
+
+	d.out.Flush()
+}
+
+func (d *dumper) error(err error, from net.Addr) {
+	if !matchSource(d.sources, from.String()) {
+		return
+	}
+
+	d.printError(time.Now(), from, err)
+	d.out.Flush()
+}
+
+func (d *dumper) matchAddress(address gosc.OSCAddressPattern) bool {
+	for _, p := range d.patterns {
+		if p.Match(string(address)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Decodes a packet into its messages.
+func (d *dumper) decodePacket(data []byte) ([]dumpedMessage, error) {
+	packet, err := gosc.ReadPacketWith(bytes.NewReader(data), d.decode)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []dumpedMessage
+	flattenPacket(packet, false, 0, &msgs)
+	return msgs, nil
+}
+
+func flattenPacket(packet gosc.OSCPacket, bundled bool, timetag gosc.OSCTimetag, msgs *[]dumpedMessage) {
+	switch p := packet.(type) {
+	case gosc.Message:
+		*msgs = append(*msgs, dumpedMessage{p, bundled, timetag})
+	case gosc.OSCBundle:
+		for _, elem := range p.Elements {
+			flattenPacket(elem, true, p.Timetag, msgs)
+		}
+	}
+}
+
+// Formats the message, preceded by its bundle's timetag if it has one.
+func (m dumpedMessage) String() string {
+	if m.bundled {
+		return fmt.Sprintf("[%s] %s", gosc.FormatArg(m.timetag), m.msg)
+	}
+
+	return m.msg.String()
+}
+
+func (d *dumper) printText(now time.Time, from net.Addr, m dumpedMessage) {
+	fmt.Fprintf(d.out, "%s %s %s\n", now.Format("15:04:05.000000"), from, m)
+}
+
+func (d *dumper) printError(now time.Time, from net.Addr, err error) {
+	if d.format == "json" {
+		d.printJSON(now, from, nil, err)
+		return
+	}
+
+	fmt.Fprintf(d.out, "%s %s error: %s\n", now.Format("15:04:05.000000"), from, err)
+}
+
+// The JSON form of a message (or an error) received by dump.
+type jsonMessage struct {
+	Time    string        `json:"time"`
+	Source  string        `json:"source"`
+	Bundle  string        `json:"bundle,omitempty"`
+	Address string        `json:"address,omitempty"`
+	Tags    string        `json:"tags,omitempty"`
+	Args    []interface{} `json:"args,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func (d *dumper) printJSON(now time.Time, from net.Addr, m *dumpedMessage, err error) {
+	j := jsonMessage{
+		Time:   now.Format(time.RFC3339Nano),
+		Source: from.String(),
+	}
+
+	if m != nil {
+		if m.bundled {
+			j.Bundle = gosc.FormatArg(m.timetag)
+		}
+		j.Address = string(m.msg.Address)
+		j.Tags = string(m.msg.TypeTags())
+		j.Args = make([]interface{}, len(m.msg.Args))
+		for i, arg := range m.msg.Args {
+			j.Args[i] = jsonArg(arg)
+		}
+	}
+
+	if err != nil {
+		j.Error = err.Error()
+	}
+
+	writeJSON(d.out, j)
+}
+
+func writeJSON(out io.Writer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Every value is converted by jsonArg first, so this shouldn't happen.
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+
+	out.Write(append(data, '\n'))
+}
+
+// Converts an argument to a value that encodes naturally as JSON. The type of
+// each argument is given by the tag string, so it isn't repeated here.
+func jsonArg(arg gosc.OSCArg) interface{} {
+	switch a := arg.(type) {
+	case gosc.OSCInt32:
+		return int64(a)
+	case gosc.OSCInt64:
+		return int64(a)
+	case gosc.OSCFloat32:
+		return jsonFloat(float64(a))
+	case gosc.OSCFloat64:
+		return jsonFloat(float64(a))
+	case gosc.OSCString:
+		return string(a)
+	case gosc.OSCStringAlt:
+		return string(a)
+	case gosc.OSCChar:
+		return string(rune(a))
+	case gosc.OSCBlob:
+		return hex.EncodeToString(a)
+	case gosc.OSCBool:
+		return bool(a)
+	case gosc.OSCNil:
+		return nil
+	case gosc.OSCMIDI:
+		return []int{int(a.Port), int(a.Status), int(a.Data1), int(a.Data2)}
+	case gosc.OSCArray:
+		elems := make([]interface{}, len(a))
+		for i, elem := range a {
+			elems[i] = jsonArg(elem)
+		}
+		return elems
+	default:
+		return gosc.FormatArg(arg)
+	}
+}
+
+// JSON has no NaN or infinities, so those are given as strings.
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+
+	return f
+}
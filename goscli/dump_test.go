@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	. "testing"
+
+	"github.com/tokenshift/gosc"
+)
+
+func TestMatchSource(t *T) {
+	inputs := map[string]struct {
+		sources  []string
+		addr     string
+		expected bool
+	}{
+		"no filters":        {nil, "10.0.0.1:9000", true},
+		"host":              {[]string{"10.0.0.1"}, "10.0.0.1:9000", true},
+		"host and port":     {[]string{"10.0.0.1:9000"}, "10.0.0.1:9000", true},
+		"other port":        {[]string{"10.0.0.1:9001"}, "10.0.0.1:9000", false},
+		"other host":        {[]string{"10.0.0.2"}, "10.0.0.1:9000", false},
+		"any of several":    {[]string{"10.0.0.2", "10.0.0.1"}, "10.0.0.1:9000", true},
+		"ipv6 host":         {[]string{"::1"}, "[::1]:9000", true},
+		"unix socket":       {[]string{"/tmp/osc.sock"}, "/tmp/osc.sock", true},
+		"other unix socket": {[]string{"/tmp/other.sock"}, "/tmp/osc.sock", false},
+	}
+
+	for name, input := range inputs {
+		if matched := matchSource(input.sources, input.addr); matched != input.expected {
+			t.Errorf("%s: expected %t, got %t", name, input.expected, matched)
+		}
+	}
+}
+
+func TestJSONArg(t *T) {
+	inputs := map[string]struct {
+		arg      gosc.OSCArg
+		expected string
+	}{
+		"int32":     {gosc.OSCInt32(-1), `-1`},
+		"int64":     {gosc.OSCInt64(1 << 40), `1099511627776`},
+		"float32":   {gosc.OSCFloat32(0.5), `0.5`},
+		"float64":   {gosc.OSCFloat64(0.1), `0.1`},
+		"nan":       {gosc.OSCFloat64(math.NaN()), `"NaN"`},
+		"infinity":  {gosc.OSCFloat32(math.Inf(-1)), `"-Inf"`},
+		"string":    {gosc.OSCString("a\"b"), `"a\"b"`},
+		"alt":       {gosc.OSCStringAlt("x"), `"x"`},
+		"char":      {gosc.OSCChar('c'), `"c"`},
+		"blob":      {gosc.OSCBlob{1, 0xff}, `"01ff"`},
+		"bool":      {gosc.OSCBool(true), `true`},
+		"nil":       {gosc.OSCNil{}, `null`},
+		"midi":      {gosc.OSCMIDI{Port: 0, Status: 0x90, Data1: 60, Data2: 100}, `[0,144,60,100]`},
+		"array":     {gosc.OSCArray{gosc.OSCInt32(1), gosc.OSCArray{gosc.OSCString("x")}}, `[1,["x"]]`},
+		"color":     {gosc.OSCRGBA{R: 1, G: 2, B: 3, A: 4}, `"#01020304"`},
+		"timetag":   {gosc.OSC_TIMETAG_IMMEDIATELY, `"immediately"`},
+		"infinitum": {gosc.OSCInfinity{}, `"inf"`},
+	}
+
+	for name, input := range inputs {
+		data, err := json.Marshal(jsonArg(input.arg))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else if string(data) != input.expected {
+			t.Errorf("%s: expected %s, got %s", name, input.expected, data)
+		}
+	}
+}
+
+func encodePacket(t *T, packet gosc.OSCPacket) []byte {
+	var out bytes.Buffer
+	if _, err := packet.WriteTo(&out); err != nil {
+		t.Fatalf("failed to encode %#v: %s", packet, err)
+	}
+
+	return out.Bytes()
+}
+
+func TestDecodePacket(t *T) {
+	a := gosc.Message{Address: "/a", Args: []gosc.OSCArg{gosc.OSCInt32(1)}}
+	b := gosc.Message{Address: "/b", Args: []gosc.OSCArg{gosc.OSCString("x")}}
+	c := gosc.Message{Address: "/c", Args: []gosc.OSCArg{}}
+	outer, inner := gosc.OSCTimetag(1 << 32), gosc.OSCTimetag(2 << 32)
+
+	inputs := map[string]struct {
+		packet   gosc.OSCPacket
+		expected []dumpedMessage
+	}{
+		"message": {a, []dumpedMessage{{msg: a}}},
+		"bundle": {gosc.OSCBundle{Timetag: outer, Elements: []gosc.OSCPacket{a, b}},
+			[]dumpedMessage{{a, true, outer}, {b, true, outer}}},
+		"nested bundle": {gosc.OSCBundle{Timetag: outer, Elements: []gosc.OSCPacket{a, gosc.OSCBundle{Timetag: inner, Elements: []gosc.OSCPacket{b}}, c}},
+			[]dumpedMessage{{a, true, outer}, {b, true, inner}, {c, true, outer}}},
+		"empty bundle": {gosc.OSCBundle{Timetag: outer, Elements: []gosc.OSCPacket{}}, nil},
+	}
+
+	d := &dumper{decode: gosc.DecodeOptions{Strict: true}}
+
+	for name, input := range inputs {
+		msgs, err := d.decodePacket(encodePacket(t, input.packet))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else {
+			expectSame(t, input.expected, msgs)
+		}
+	}
+}
+
+func TestDecodePacketErrors(t *T) {
+	missingTags := []byte{47, 97, 0, 0}
+	bundle := encodePacket(t, gosc.OSCBundle{Timetag: 1, Elements: []gosc.OSCPacket{gosc.Message{Address: "/a", Args: []gosc.OSCArg{}}}})
+
+	inputs := map[string][]byte{
+		"empty":              {},
+		"missing tag string": missingTags,
+		"truncated argument": {47, 97, 0, 0, 44, 105, 0, 0, 0, 0},
+		"unknown tag":        {47, 97, 0, 0, 44, 122, 0, 0},
+		"truncated bundle":   bundle[:len(bundle) - 2],
+	}
+
+	strict := &dumper{decode: gosc.DecodeOptions{Strict: true}}
+
+	for name, input := range inputs {
+		if _, err := strict.decodePacket(input); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// Lenient decoding accepts a message without a tag string.
+	lenient := &dumper{decode: gosc.DecodeOptions{AllowMissingTagString: true}}
+	msgs, err := lenient.decodePacket(missingTags)
+	expectNil(t, err)
+	expectSame(t, 1, len(msgs))
+	expectSame(t, gosc.OSCAddressPattern("/a"), msgs[0].msg.Address)
+
+	// The options apply to messages in bundles too.
+	bundled := append([]byte("#bundle\x00"), 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 8, 47, 97, 0, 0, 44, 122, 0, 0)
+	_, err = strict.decodePacket(bundled)
+	if err == nil {
+		t.Errorf("expected an error for an unknown tag in a bundle")
+	}
+
+	lenient.decode.UnknownTagPolicy = gosc.UnknownTagRaw
+	msgs, err = lenient.decodePacket(bundled)
+	expectNil(t, err)
+	expectSame(t, []dumpedMessage{{gosc.Message{Address: "/a", Args: []gosc.OSCArg{gosc.OSCRaw{Tags: "z", Data: []byte{}}}}, true, 1 << 32}}, msgs)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+/**
+ * Command line interface to send and receive OSC messages.
+ *
+ * $ goscli <command> [options] [arguments]
+ *
+ * Run "goscli help <command>" for the options of each command.
+ */
+
+type command struct {
+	run   func(args []string) error
+	usage string
+}
+
+var commands = map[string]command{
+	"dump":   {runDump, dumpUsage},
+	"record": {runRecord, recordUsage},
+	"replay": {runReplay, replayUsage},
+	"send":   {runSend, sendUsage},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+
+	if name == "help" || name == "-h" || name == "--help" {
+		if len(args) > 0 {
+			if cmd, ok := commands[args[0]]; ok {
+				fmt.Fprint(os.Stderr, cmd.usage)
+				return
+			}
+		}
+		printUsage()
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "goscli: unknown command \"%s\"\n\n", name)
+		printUsage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "goscli %s: %s\n", name, err)
+		if _, ok := err.(usageError); ok {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: goscli <command> [options] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run \"goscli help <command>\" for details.")
+}
+
+// An error in the command line, rather than in running the command.
+type usageError string
+
+func usageErrorf(f string, args ...interface{}) usageError {
+	return usageError(fmt.Sprintf(f, args...))
+}
+
+func (e usageError) Error() string {
+	return string(e)
+}
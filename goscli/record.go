@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const recordUsage = `usage: goscli record [options] -o <file> <listen>
+
+Records the OSC packets received on the listen address (a URL as for dump)
+until interrupted or terminated, with the time each one arrived and where it
+came from, so that they can be played back later with replay, e.g.
+
+  goscli record -o show.oscrec :9000
+
+Packets are stored exactly as received, including any that can't be decoded.
+
+Options:
+`
+
+func runRecord(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, recordUsage)
+		flags.PrintDefaults()
+	}
+
+	var sources stringList
+	output := flags.String("o", "", "file to write the recording to (required)")
+	flags.Var(&sources, "source", "only record packets from this host or host:port (repeatable)")
+	slip := flags.Bool("slip", false, "expect stream transports to be framed with SLIP (OSC 1.1) instead of a size prefix")
+	verbose := flags.Bool("v", false, "print a line for each packet recorded")
+
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	if flags.NArg() != 1 || *output == "" {
+		flags.Usage()
+		return usageErrorf("expected -o and an address to listen on")
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	w, err := newRecordingWriter(f, start)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var count, size int
+	var writeErr error
+
+	err = listen(ctx, flags.Arg(0), *slip, func(data []byte, from net.Addr) {
+		if writeErr != nil || !matchSource(sources, from.String()) {
+			return
+		}
+
+		now := time.Now()
+		if writeErr = w.write(now, from.String(), data); writeErr != nil {
+			stop()
+			return
+		}
+
+		count++
+		size += len(data)
+
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "%s %s %d bytes\n", now.Sub(start).Round(time.Millisecond), from, len(data))
+		}
+	}, func(err error, from net.Addr) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", from, err)
+	})
+
+	fmt.Fprintf(os.Stderr, "recorded %d packets (%d bytes) in %s\n", count, size, time.Since(start).Round(time.Millisecond))
+
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tokenshift/gosc"
+)
+
+/**
+ * Recordings of OSC sessions (.oscrec files), as written by record and read
+ * by replay. All numbers are big-endian, as in OSC itself. A recording starts
+ * with a header:
+ *
+ *   "gosc-rec" (8 bytes), version (uint32), start time (int64, Unix ns)
+ *
+ * followed by a record for each packet received:
+ *
+ *   offset from the start (int64, ns)
+ *   source address length (uint16), source address
+ *   packet length (int32), packet
+ */
+
+const recordingMagic = "gosc-rec"
+const recordingVersion = 1
+
+// A packet in a recording.
+type recordedPacket struct {
+	// When the packet was received, relative to the start of the recording.
+	offset time.Duration
+
+	// Where it came from, e.g. "192.168.1.20:53411".
+	source string
+
+	// The packet as received, without any stream framing.
+	data []byte
+}
+
+type recordingWriter struct {
+	out   *bufio.Writer
+	start time.Time
+}
+
+// Writes the header of a recording starting at the given time.
+func newRecordingWriter(out io.Writer, start time.Time) (*recordingWriter, error) {
+	w := &recordingWriter{out: bufio.NewWriter(out), start: start}
+
+	w.out.WriteString(recordingMagic)
+	binary.Write(w.out, binary.BigEndian, uint32(recordingVersion))
+	binary.Write(w.out, binary.BigEndian, start.UnixNano())
+	if err := w.out.Flush(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Writes a packet received at the given time. Each packet is flushed as it's
+// written, so that a recording stays readable up to its last packet even if
+// the recorder is killed.
+func (w *recordingWriter) write(at time.Time, source string, data []byte) error {
+	if len(source) > 0xffff {
+		source = source[:0xffff]
+	}
+
+	binary.Write(w.out, binary.BigEndian, int64(at.Sub(w.start)))
+	binary.Write(w.out, binary.BigEndian, uint16(len(source)))
+	w.out.WriteString(source)
+	binary.Write(w.out, binary.BigEndian, int32(len(data)))
+	w.out.Write(data)
+	return w.out.Flush()
+}
+
+type recordingReader struct {
+	in    *bufio.Reader
+	start time.Time
+}
+
+// Reads the header of a recording.
+func newRecordingReader(in io.Reader) (*recordingReader, error) {
+	r := &recordingReader{in: bufio.NewReader(in)}
+
+	var header struct {
+		Magic   [8]byte
+		Version uint32
+		Start   int64
+	}
+	if err := binary.Read(r.in, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("not a recording: %w", err)
+	}
+
+	if string(header.Magic[:]) != recordingMagic {
+		return nil, fmt.Errorf("not a recording")
+	} else if header.Version != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", header.Version)
+	}
+
+	r.start = time.Unix(0, header.Start)
+	return r, nil
+}
+
+// Reads the next packet. Returns io.EOF at the end of the recording, or
+// io.ErrUnexpectedEOF if it ends part way through a packet (e.g. because the
+// recorder was killed).
+func (r *recordingReader) read() (recordedPacket, error) {
+	var p recordedPacket
+
+	var offset int64
+	if err := binary.Read(r.in, binary.BigEndian, &offset); err != nil {
+		return p, err
+	}
+	p.offset = time.Duration(offset)
+
+	var sourceLen uint16
+	if err := binary.Read(r.in, binary.BigEndian, &sourceLen); err != nil {
+		return p, unexpectedEOF(err)
+	}
+
+	source := make([]byte, sourceLen)
+	if _, err := io.ReadFull(r.in, source); err != nil {
+		return p, unexpectedEOF(err)
+	}
+	p.source = string(source)
+
+	var size int32
+	if err := binary.Read(r.in, binary.BigEndian, &size); err != nil {
+		return p, unexpectedEOF(err)
+	}
+
+	if size < 0 || size > gosc.OSC_MAX_FRAME_SIZE {
+		return p, fmt.Errorf("invalid packet size %d at %s", size, p.offset)
+	}
+
+	p.data = make([]byte, size)
+	if _, err := io.ReadFull(r.in, p.data); err != nil {
+		return p, unexpectedEOF(err)
+	}
+
+	return p, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	. "testing"
+	"time"
+)
+
+func TestRecordingRoundTrip(t *T) {
+	start := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	packets := []recordedPacket{
+		{0, "10.0.0.1:9000", []byte{47, 97, 0, 0, 44, 0, 0, 0}},
+		{1500 * time.Millisecond, "/tmp/osc.sock", []byte{}},
+		{time.Hour, "", []byte{0xc0, 0xdb, 0, 1}},
+	}
+
+	var out bytes.Buffer
+	w, err := newRecordingWriter(&out, start)
+	expectNil(t, err)
+	expectSame(t, 20, out.Len())
+
+	for _, p := range packets {
+		size := out.Len()
+		expectNil(t, w.write(start.Add(p.offset), p.source, p.data))
+
+		// Each packet is written out straight away.
+		expectSame(t, size + 8 + 2 + len(p.source) + 4 + len(p.data), out.Len())
+	}
+
+	r, err := newRecordingReader(bytes.NewReader(out.Bytes()))
+	expectNil(t, err)
+	expectSame(t, start.UnixNano(), r.start.UnixNano())
+
+	for _, expected := range packets {
+		p, err := r.read()
+		expectNil(t, err)
+		expectSame(t, expected, p)
+	}
+
+	_, err = r.read()
+	expectSame(t, io.EOF, err)
+}
+
+func TestRecordingLongSource(t *T) {
+	var out bytes.Buffer
+	w, err := newRecordingWriter(&out, time.Unix(0, 0))
+	expectNil(t, err)
+	expectNil(t, w.write(time.Unix(0, 0), strings.Repeat("x", 0x10000), []byte{1}))
+
+	r, err := newRecordingReader(&out)
+	expectNil(t, err)
+	p, err := r.read()
+	expectNil(t, err)
+	expectSame(t, 0xffff, len(p.source))
+	expectSame(t, []byte{1}, p.data)
+}
+
+func TestRecordingTruncated(t *T) {
+	var out bytes.Buffer
+	w, _ := newRecordingWriter(&out, time.Unix(0, 0))
+	w.write(time.Unix(1, 0), "a", []byte{1, 2, 3, 4})
+	complete := out.Len()
+	w.write(time.Unix(2, 0), "b", []byte{5, 6, 7, 8})
+
+	// Cut off at every point in the second packet, as if the recorder had
+	// been killed while writing it.
+	for n := complete + 1; n < out.Len(); n++ {
+		r, err := newRecordingReader(bytes.NewReader(out.Bytes()[:n]))
+		expectNil(t, err)
+
+		p, err := r.read()
+		expectNil(t, err)
+		expectSame(t, time.Second, p.offset)
+
+		if _, err = r.read(); err != io.ErrUnexpectedEOF {
+			t.Errorf("%d bytes: expected io.ErrUnexpectedEOF, got %#v", n, err)
+		}
+	}
+}
+
+func TestRecordingErrors(t *T) {
+	var out bytes.Buffer
+	newRecordingWriter(&out, time.Unix(0, 0))
+	header := out.Bytes()
+
+	inputs := map[string]struct {
+		data []byte
+		err  string
+	}{
+		"empty":            {nil, "not a recording"},
+		"truncated header": {header[:12], "not a recording"},
+		"bad magic":        {append([]byte("gosc-rek"), header[8:]...), "not a recording"},
+		"future version":   {append(append([]byte("gosc-rec"), 0, 0, 0, 2), header[12:]...), "unsupported recording version 2"},
+	}
+
+	for name, input := range inputs {
+		_, err := newRecordingReader(bytes.NewReader(input.data))
+		if err == nil || !strings.Contains(err.Error(), input.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, input.err, err)
+		}
+	}
+
+	r, err := newRecordingReader(bytes.NewReader(append(append([]byte{}, header...), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff)))
+	expectNil(t, err)
+	_, err = r.read()
+	if err == nil || !strings.Contains(err.Error(), "invalid packet size -1") {
+		t.Errorf("expected an invalid packet size error, got %v", err)
+	}
+}
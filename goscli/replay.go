@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tokenshift/gosc"
+)
+
+const replayUsage = `usage: goscli replay [options] <file> <target>
+
+Plays back a recording made with record, sending each packet to the target (a
+URL as for send) with the same timing as when it was recorded, e.g.
+
+  goscli replay show.oscrec udp://localhost:9000
+  goscli replay -speed 2 -from 1m30s -to 2m show.oscrec udp://localhost:9000
+  goscli replay -n 0 -address '/light/*' show.oscrec udp://localhost:9000
+
+Times for -from and -to are measured from the start of the recording. With
+-address, messages inside bundles are filtered individually, and packets that
+can't be decoded are skipped.
+
+Options:
+`
+
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, replayUsage)
+		flags.PrintDefaults()
+	}
+
+	var addresses, sources stringList
+	speed := flags.Float64("speed", 1, "playback speed, e.g. 2 for twice as fast (0 = no delays)")
+	count := flags.Int("n", 1, "number of times to play the recording (0 = loop until interrupted)")
+	from := flags.Duration("from", 0, "skip packets received before this time")
+	to := flags.Duration("to", 0, "stop at packets received after this time (0 = the end)")
+	flags.Var(&addresses, "address", "only send messages matching this address pattern (repeatable)")
+	flags.Var(&sources, "source", "only send packets recorded from this host or host:port (repeatable)")
+	slip := flags.Bool("slip", false, "frame stream transports with SLIP (OSC 1.1) instead of a size prefix")
+	verbose := flags.Bool("v", false, "print a line for each packet sent")
+
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return usageErrorf("expected a recording and a target")
+	}
+
+	if *speed < 0 || *count < 0 || *from < 0 || *to < 0 {
+		return usageErrorf("-speed, -n, -from and -to can't be negative")
+	} else if *to > 0 && *to < *from {
+		return usageErrorf("-to can't be before -from")
+	}
+
+	var patterns []*gosc.Pattern
+	for _, a := range addresses {
+		p, err := gosc.CompilePattern(a)
+		if err != nil {
+			return usageErrorf("invalid address pattern \"%s\": %s", a, err)
+		}
+		patterns = append(patterns, p)
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	t, err := dialTarget(flags.Arg(1), *slip)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	sent := 0
+
+	for played := 0; (*count == 0 || played < *count) && ctx.Err() == nil; played++ {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		r, err := newRecordingReader(f)
+		if err != nil {
+			return err
+		}
+
+		loopStart := time.Now()
+
+		for ctx.Err() == nil {
+			p, err := r.read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("failed to read recording after %d packets: %w", sent, err)
+			}
+
+			if p.offset < *from || !matchSource(sources, p.source) {
+				continue
+			} else if *to > 0 && p.offset > *to {
+				break
+			}
+
+			data, ok := filterPacket(p.data, patterns)
+			if !ok {
+				continue
+			}
+
+			if *speed > 0 {
+				due := loopStart.Add(time.Duration(float64(p.offset - *from) / *speed))
+				if !sleepUntil(ctx, due) {
+					break
+				}
+			}
+
+			framed, err := t.frame(data)
+			if err != nil {
+				return err
+			}
+
+			if err := t.write(framed); err != nil {
+				return fmt.Errorf("failed after %d packets: %w", sent, err)
+			}
+			sent++
+
+			if *verbose {
+				fmt.Fprintf(os.Stderr, "%s %s %d bytes\n", p.offset.Round(time.Millisecond), p.source, len(data))
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "sent %d packets in %s\n", sent, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// Waits until the given time, returning false if the context is done first.
+func sleepUntil(ctx context.Context, due time.Time) bool {
+	wait := time.Until(due)
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Filters a packet by address, returning the packet to send (which may be a
+// bundle with some of its messages removed) and whether there's anything left
+// to send. Without patterns every packet is sent unchanged, even ones that
+// can't be decoded.
+func filterPacket(data []byte, patterns []*gosc.Pattern) ([]byte, bool) {
+	if len(patterns) == 0 {
+		return data, true
+	}
+
+	packet, err := gosc.ReadPacket(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+
+	filtered, changed := filterElements(packet, patterns)
+	if filtered == nil {
+		return nil, false
+	} else if !changed {
+		return data, true
+	}
+
+	var out bytes.Buffer
+	if _, err := filtered.WriteTo(&out); err != nil {
+		return nil, false
+	}
+
+	return out.Bytes(), true
+}
+
+// Returns the parts of a packet that match the patterns (or nil if nothing
+// does), and whether anything was removed.
+func filterElements(packet gosc.OSCPacket, patterns []*gosc.Pattern) (gosc.OSCPacket, bool) {
+	switch p := packet.(type) {
+	case gosc.Message:
+		for _, pattern := range patterns {
+			if pattern.Match(string(p.Address)) {
+				return p, false
+			}
+		}
+		return nil, true
+	case gosc.OSCBundle:
+		kept := gosc.OSCBundle{Timetag: p.Timetag, Elements: []gosc.OSCPacket{}}
+		changed := false
+		for _, elem := range p.Elements {
+			e, c := filterElements(elem, patterns)
+			if e != nil {
+				kept.Elements = append(kept.Elements, e)
+			}
+			changed = changed || c
+		}
+		if len(kept.Elements) == 0 {
+			return nil, true
+		}
+		return kept, changed
+	}
+
+	return nil, true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	. "testing"
+
+	"github.com/tokenshift/gosc"
+)
+
+func TestFilterPacket(t *T) {
+	a := gosc.Message{Address: "/light/1", Args: []gosc.OSCArg{gosc.OSCFloat32(0.5)}}
+	b := gosc.Message{Address: "/sound/1", Args: []gosc.OSCArg{gosc.OSCInt32(1)}}
+	c := gosc.Message{Address: "/light/2", Args: []gosc.OSCArg{}}
+	tt := gosc.OSCTimetag(1 << 32)
+
+	nested := gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{
+		a,
+		gosc.OSCBundle{Timetag: tt + 1, Elements: []gosc.OSCPacket{b}},
+		gosc.OSCBundle{Timetag: tt + 2, Elements: []gosc.OSCPacket{b, c}},
+	}}
+
+	lights := []*gosc.Pattern{gosc.MustCompilePattern("/light/*")}
+
+	inputs := map[string]struct {
+		packet   gosc.OSCPacket
+		patterns []*gosc.Pattern
+		expected gosc.OSCPacket
+	}{
+		"no patterns":      {nested, nil, nested},
+		"matching message": {a, lights, a},
+		"other message":    {b, lights, nil},
+		"any pattern":      {b, []*gosc.Pattern{gosc.MustCompilePattern("/x"), gosc.MustCompilePattern("/sound/1")}, b},
+		"whole bundle": {gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{a, c}}, lights,
+			gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{a, c}}},
+		"part of bundle": {gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{a, b, c}}, lights,
+			gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{a, c}}},
+		"nested bundles": {nested, lights, gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{
+			a,
+			gosc.OSCBundle{Timetag: tt + 2, Elements: []gosc.OSCPacket{c}},
+		}}},
+		"nothing in bundle": {gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{b}}, lights, nil},
+		"empty bundle":      {gosc.OSCBundle{Timetag: tt, Elements: []gosc.OSCPacket{}}, lights, nil},
+	}
+
+	for name, input := range inputs {
+		data, ok := filterPacket(encodePacket(t, input.packet), input.patterns)
+		if input.expected == nil {
+			if ok {
+				t.Errorf("%s: expected the packet to be dropped, got %v", name, data)
+			}
+		} else if !ok {
+			t.Errorf("%s: expected a packet, but it was dropped", name)
+		} else {
+			expectSame(t, encodePacket(t, input.expected), data)
+		}
+	}
+}
+
+func TestFilterPacketUndecodable(t *T) {
+	data := []byte{47, 97, 0, 0, 44, 105, 0, 0}
+
+	// Without patterns, packets are sent as recorded.
+	filtered, ok := filterPacket(data, nil)
+	expectSame(t, true, ok)
+	expectSame(t, data, filtered)
+
+	_, ok = filterPacket(data, []*gosc.Pattern{gosc.MustCompilePattern("/a")})
+	expectSame(t, false, ok)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tokenshift/gosc"
+)
+
+const sendUsage = `usage: goscli send [options] <target> <address> [arguments...]
+
+Sends an OSC message. The target is a URL: udp://host:port (the default if
+there's no scheme), tcp://host:port, unix:///path or unixgram:///path.
+
+Argument types are inferred: integers are sent as int32 (or int64 if they
+don't fit), numbers with a decimal point or exponent as float32, true and
+false as booleans, nil as nil, and anything else as a string. Use -t to give
+the types explicitly, e.g.
+
+  goscli send udp://localhost:9000 /mixer/1/fader 0.5
+  goscli send -t ,sd udp://localhost:9000 /label 42 2.5
+
+Explicit tags can be any of i h f d s S c b t r m T F N I, and [ ] for arrays.
+Blobs (b), colors (r) and MIDI messages (m) are given in hex, chars (c) as a
+single character, and timetags (t) as "now", "immediately", an offset from
+now like "+1.5s", an RFC 3339 time, or a raw 64-bit NTP value. T, F, N and
+I take no value.
+
+Options:
+`
+
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, sendUsage)
+		flags.PrintDefaults()
+	}
+
+	tags := flags.String("t", "", "explicit tag string for the arguments, e.g. ,ifs")
+	count := flags.Int("n", 1, "number of times to send the message (0 = until interrupted)")
+	rate := flags.Float64("rate", 0, "maximum messages per second (0 = unlimited)")
+	slip := flags.Bool("slip", false, "frame stream transports with SLIP (OSC 1.1) instead of a size prefix")
+	verbose := flags.Bool("v", false, "print the message before sending it")
+
+	if err := flags.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return usageErrorf("expected a target and an address")
+	}
+
+	if *count < 0 || *rate < 0 {
+		return usageErrorf("-n and -rate can't be negative")
+	}
+
+	address := gosc.OSCAddressPattern(flags.Arg(1))
+	values := flags.Args()[2:]
+
+	var oscArgs []gosc.OSCArg
+	var err error
+	if *tags != "" {
+		oscArgs, err = parseTypedArgs(*tags, values)
+	} else {
+		oscArgs = inferArgs(values)
+	}
+	if err != nil {
+		return usageError(err.Error())
+	}
+
+	var packet bytes.Buffer
+	if _, err := gosc.WriteMessage(&packet, address, oscArgs...); err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Fprintln(os.Stderr, gosc.Message{Address: address, Args: oscArgs})
+	}
+
+	t, err := dialTarget(flags.Arg(0), *slip)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	framed, err := t.frame(packet.Bytes())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var tick <-chan time.Time
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	start := time.Now()
+	sent := 0
+
+	for ; *count == 0 || sent < *count; sent++ {
+		if tick != nil && sent > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err := t.write(framed); err != nil {
+			return fmt.Errorf("failed after %d messages: %w", sent, err)
+		}
+	}
+
+	if *count != 1 {
+		elapsed := time.Since(start)
+		fmt.Fprintf(os.Stderr, "sent %d messages in %s (%.0f/s)\n", sent, elapsed.Round(time.Millisecond), float64(sent) / elapsed.Seconds())
+	}
+
+	return nil
+}
+
+// Infers the type of each argument from its text.
+func inferArgs(values []string) []gosc.OSCArg {
+	args := make([]gosc.OSCArg, len(values))
+
+	for i, v := range values {
+		args[i] = inferArg(v)
+	}
+
+	return args
+}
+
+func inferArg(v string) gosc.OSCArg {
+	switch v {
+	case "true":
+		return gosc.OSCBool(true)
+	case "false":
+		return gosc.OSCBool(false)
+	case "nil":
+		return gosc.OSCNil{}
+	}
+
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if int64(int32(i)) == i {
+			return gosc.OSCInt32(i)
+		}
+		return gosc.OSCInt64(i)
+	}
+
+	// ParseFloat also accepts words like "inf" and "nan", which are more
+	// likely meant as strings.
+	if strings.ContainsAny(v, "0123456789") {
+		if f, err := strconv.ParseFloat(v, 32); err == nil {
+			return gosc.OSCFloat32(f)
+		}
+	}
+
+	return gosc.OSCString(v)
+}
+
+// Parses the arguments according to an explicit tag string.
+func parseTypedArgs(tags string, values []string) ([]gosc.OSCArg, error) {
+	tags = strings.TrimPrefix(tags, ",")
+
+	// The arguments at each level of array nesting.
+	stack := [][]gosc.OSCArg{{}}
+
+	for i := 0; i < len(tags); i++ {
+		tag := gosc.OSCTypeTag(tags[i])
+		top := len(stack) - 1
+
+		switch tag {
+		case gosc.OSC_ETYPE_ARRAY_START:
+			stack = append(stack, []gosc.OSCArg{})
+			continue
+		case gosc.OSC_ETYPE_ARRAY_END:
+			if top == 0 {
+				return nil, fmt.Errorf("unmatched ']' in tag string")
+			}
+			stack[top-1] = append(stack[top-1], gosc.OSCArray(stack[top]))
+			stack = stack[:top]
+			continue
+		case gosc.OSC_ETYPE_TRUE, gosc.OSC_ETYPE_FALSE:
+			stack[top] = append(stack[top], gosc.OSCBool(tag == gosc.OSC_ETYPE_TRUE))
+			continue
+		case gosc.OSC_ETYPE_NIL:
+			stack[top] = append(stack[top], gosc.OSCNil{})
+			continue
+		case gosc.OSC_ETYPE_INFINITY:
+			stack[top] = append(stack[top], gosc.OSCInfinity{})
+			continue
+		}
+
+		if len(values) == 0 {
+			return nil, fmt.Errorf("no value for '%c' at position %d in tag string", tag, i)
+		}
+
+		arg, err := parseArg(tag, values[0])
+		if err != nil {
+			return nil, fmt.Errorf("argument \"%s\" for '%c': %s", values[0], tag, err)
+		}
+
+		stack[top] = append(stack[top], arg)
+		values = values[1:]
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unterminated '[' in tag string")
+	}
+
+	if len(values) > 0 {
+		return nil, fmt.Errorf("%d more arguments than the tag string describes", len(values))
+	}
+
+	return stack[0], nil
+}
+
+func parseArg(tag gosc.OSCTypeTag, v string) (gosc.OSCArg, error) {
+	switch tag {
+	case gosc.OSC_TYPE_INT32:
+		i, err := strconv.ParseInt(v, 0, 32)
+		return gosc.OSCInt32(i), err
+	case gosc.OSC_ETYPE_INT64:
+		i, err := strconv.ParseInt(v, 0, 64)
+		return gosc.OSCInt64(i), err
+	case gosc.OSC_TYPE_FLOAT32:
+		f, err := strconv.ParseFloat(v, 32)
+		return gosc.OSCFloat32(f), err
+	case gosc.OSC_ETYPE_FLOAT64:
+		f, err := strconv.ParseFloat(v, 64)
+		return gosc.OSCFloat64(f), err
+	case gosc.OSC_TYPE_STRING:
+		return gosc.OSCString(v), nil
+	case gosc.OSC_ETYPE_STRING_ALT:
+		return gosc.OSCStringAlt(v), nil
+	case gosc.OSC_ETYPE_CHAR:
+		if len(v) != 1 {
+			return nil, fmt.Errorf("expected a single character")
+		}
+		return gosc.OSCChar(v[0]), nil
+	case gosc.OSC_TYPE_BLOB:
+		b, err := parseHex(v, -1)
+		return gosc.OSCBlob(b), err
+	case gosc.OSC_ETYPE_RGBA:
+		b, err := parseHex(v, 4)
+		if err != nil {
+			return nil, err
+		}
+		return gosc.OSCRGBA{R: b[0], G: b[1], B: b[2], A: b[3]}, nil
+	case gosc.OSC_ETYPE_MIDI:
+		b, err := parseHex(v, 4)
+		if err != nil {
+			return nil, err
+		}
+		return gosc.OSCMIDI{Port: b[0], Status: b[1], Data1: b[2], Data2: b[3]}, nil
+	case gosc.OSC_ETYPE_TIMETAG:
+		return parseTimetag(v)
+	}
+
+	return nil, fmt.Errorf("unsupported type tag")
+}
+
+// Parses hex digits (with an optional 0x prefix), requiring exactly n bytes
+// unless n is negative.
+func parseHex(v string, n int) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(v, "0x"))
+	if err != nil {
+		return nil, err
+	}
+
+	if n >= 0 && len(b) != n {
+		return nil, fmt.Errorf("expected %d bytes of hex, got %d", n, len(b))
+	}
+
+	return b, nil
+}
+
+func parseTimetag(v string) (gosc.OSCArg, error) {
+	switch {
+	case v == "now":
+		return gosc.OSCTimetagFromTime(time.Now()), nil
+	case v == "immediately":
+		return gosc.OSC_TIMETAG_IMMEDIATELY, nil
+	case strings.HasPrefix(v, "+"):
+		d, err := time.ParseDuration(v[1:])
+		return gosc.OSCTimetagFromTime(time.Now().Add(d)), err
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return gosc.OSCTimetagFromTime(t), nil
+	}
+
+	n, err := strconv.ParseUint(v, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected now, immediately, +duration, an RFC 3339 time or an NTP value")
+	}
+
+	return gosc.OSCTimetag(n), nil
+}
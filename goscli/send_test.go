@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math"
+	"strings"
+	. "testing"
+	"time"
+
+	"github.com/tokenshift/gosc"
+)
+
+func TestInferArg(t *T) {
+	inputs := map[string]gosc.OSCArg{
+		"true":       gosc.OSCBool(true),
+		"false":      gosc.OSCBool(false),
+		"nil":        gosc.OSCNil{},
+		"42":         gosc.OSCInt32(42),
+		"-7":         gosc.OSCInt32(-7),
+		"2147483648": gosc.OSCInt64(math.MaxInt32 + 1),
+		"0.5":        gosc.OSCFloat32(0.5),
+		"1e3":        gosc.OSCFloat32(1000),
+		"inf":        gosc.OSCString("inf"),
+		"NaN":        gosc.OSCString("NaN"),
+		"1.2.3":      gosc.OSCString("1.2.3"),
+		"hello":      gosc.OSCString("hello"),
+		"":           gosc.OSCString(""),
+	}
+
+	for input, expected := range inputs {
+		if arg := inferArg(input); arg != expected {
+			t.Errorf("%q: expected %#v, got %#v", input, expected, arg)
+		}
+	}
+}
+
+func TestParseTypedArgs(t *T) {
+	inputs := map[string]struct {
+		tags     string
+		values   []string
+		expected []gosc.OSCArg
+	}{
+		"basic types":   {",ifs", []string{"1", "2.5", "x"}, []gosc.OSCArg{gosc.OSCInt32(1), gosc.OSCFloat32(2.5), gosc.OSCString("x")}},
+		"without comma": {"hdS", []string{"1", "2.5", "x"}, []gosc.OSCArg{gosc.OSCInt64(1), gosc.OSCFloat64(2.5), gosc.OSCStringAlt("x")}},
+		"no values":     {",TFNI", nil, []gosc.OSCArg{gosc.OSCBool(true), gosc.OSCBool(false), gosc.OSCNil{}, gosc.OSCInfinity{}}},
+		"empty":         {",", nil, []gosc.OSCArg{}},
+		"array":         {",[ii]s", []string{"1", "2", "x"}, []gosc.OSCArg{gosc.OSCArray{gosc.OSCInt32(1), gosc.OSCInt32(2)}, gosc.OSCString("x")}},
+		"nested arrays": {",[[i]T]", []string{"1"}, []gosc.OSCArg{gosc.OSCArray{gosc.OSCArray{gosc.OSCInt32(1)}, gosc.OSCBool(true)}}},
+		"empty array":   {",[]", nil, []gosc.OSCArg{gosc.OSCArray{}}},
+		"hex and chars": {",bcrm", []string{"0x0102", "a", "01020304", "00904064"}, []gosc.OSCArg{gosc.OSCBlob{1, 2}, gosc.OSCChar('a'), gosc.OSCRGBA{R: 1, G: 2, B: 3, A: 4}, gosc.OSCMIDI{Port: 0, Status: 0x90, Data1: 0x40, Data2: 0x64}}},
+		"timetag":       {",t", []string{"immediately"}, []gosc.OSCArg{gosc.OSC_TIMETAG_IMMEDIATELY}},
+	}
+
+	for name, input := range inputs {
+		args, err := parseTypedArgs(input.tags, input.values)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else {
+			expectSame(t, input.expected, args)
+		}
+	}
+}
+
+func TestParseTypedArgsErrors(t *T) {
+	inputs := map[string]struct {
+		tags   string
+		values []string
+		err    string
+	}{
+		"unmatched end":    {",]", nil, "unmatched ']'"},
+		"unterminated":     {",[i", []string{"1"}, "unterminated '['"},
+		"missing value":    {",ii", []string{"1"}, "no value for 'i' at position 1"},
+		"extra values":     {",i", []string{"1", "2", "3"}, "2 more arguments"},
+		"bad int":          {",i", []string{"x"}, "argument \"x\" for 'i'"},
+		"unsupported type": {",z", []string{"1"}, "unsupported type tag"},
+	}
+
+	for name, input := range inputs {
+		_, err := parseTypedArgs(input.tags, input.values)
+		if err == nil || !strings.Contains(err.Error(), input.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, input.err, err)
+		}
+	}
+}
+
+func TestParseArg(t *T) {
+	inputs := map[string]struct {
+		tag      gosc.OSCTypeTag
+		value    string
+		expected gosc.OSCArg
+	}{
+		"hex int":      {gosc.OSC_TYPE_INT32, "0x10", gosc.OSCInt32(16)},
+		"large int64":  {gosc.OSC_ETYPE_INT64, "3000000000", gosc.OSCInt64(3000000000)},
+		"float64":      {gosc.OSC_ETYPE_FLOAT64, "0.1", gosc.OSCFloat64(0.1)},
+		"empty blob":   {gosc.OSC_TYPE_BLOB, "", gosc.OSCBlob{}},
+		"raw timetag":  {gosc.OSC_ETYPE_TIMETAG, "0x100000000", gosc.OSCTimetag(1 << 32)},
+		"empty string": {gosc.OSC_TYPE_STRING, "", gosc.OSCString("")},
+	}
+
+	for name, input := range inputs {
+		arg, err := parseArg(input.tag, input.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else {
+			expectSame(t, input.expected, arg)
+		}
+	}
+
+	invalid := map[string]struct {
+		tag   gosc.OSCTypeTag
+		value string
+	}{
+		"int32 overflow": {gosc.OSC_TYPE_INT32, "3000000000"},
+		"bad float":      {gosc.OSC_TYPE_FLOAT32, "x"},
+		"long char":      {gosc.OSC_ETYPE_CHAR, "ab"},
+		"empty char":     {gosc.OSC_ETYPE_CHAR, ""},
+		"bad hex":        {gosc.OSC_TYPE_BLOB, "zz"},
+		"short color":    {gosc.OSC_ETYPE_RGBA, "010203"},
+		"long midi":      {gosc.OSC_ETYPE_MIDI, "0102030405"},
+	}
+
+	for name, input := range invalid {
+		if _, err := parseArg(input.tag, input.value); err == nil {
+			t.Errorf("%s: expected an error for %q", name, input.value)
+		}
+	}
+}
+
+func TestParseTimetag(t *T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.UTC)
+
+	inputs := map[string]gosc.OSCArg{
+		"immediately":                 gosc.OSC_TIMETAG_IMMEDIATELY,
+		"2024-03-01T12:30:00.5Z":      gosc.OSCTimetagFromTime(at),
+		"2024-03-01T13:30:00.5+01:00": gosc.OSCTimetagFromTime(at),
+		"16384":                       gosc.OSCTimetag(16384),
+		"0xe9880e4880000000":          gosc.OSCTimetag(0xe9880e4880000000),
+	}
+
+	for input, expected := range inputs {
+		tt, err := parseTimetag(input)
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", input, err)
+		} else if tt != expected {
+			t.Errorf("%q: expected %#v, got %#v", input, expected, tt)
+		}
+	}
+
+	// Relative times can only be checked against a range.
+	relative := map[string]time.Duration{
+		"now":   0,
+		"+1.5s": 1500 * time.Millisecond,
+		"+-1m":  -time.Minute,
+	}
+
+	for input, offset := range relative {
+		before := time.Now().Add(offset)
+		tt, err := parseTimetag(input)
+		after := time.Now().Add(offset)
+
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", input, err)
+			continue
+		}
+
+		// Timetags have a resolution of about 233 picoseconds, so allow a
+		// little rounding.
+		at := tt.(gosc.OSCTimetag).Time()
+		if at.Before(before.Add(-time.Microsecond)) || at.After(after.Add(time.Microsecond)) {
+			t.Errorf("%q: expected a time between %s and %s, got %s", input, before, after, at)
+		}
+	}
+
+	for _, input := range []string{"", "later", "+1x", "2024-03-01", "-1"} {
+		if _, err := parseTimetag(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tokenshift/gosc"
+)
+
+// Parses a URL naming a transport and an address:
+//
+//	udp://host:port          datagrams (the default, if there's no scheme)
+//	tcp://host:port          stream
+//	unix:///path/to/socket   stream
+//	unixgram:///path/to/sock datagrams
+//
+// Returns the network (as used by the net package), the address, and whether
+// the transport is a stream.
+func parseURL(url string) (string, string, bool, error) {
+	network, address := "udp", url
+	if i := strings.Index(url, "://"); i >= 0 {
+		network, address = url[:i], url[i+3:]
+	}
+
+	var stream bool
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+	case "tcp", "tcp4", "tcp6", "unix":
+		stream = true
+	default:
+		return "", "", false, usageErrorf("unsupported scheme \"%s\" in \"%s\"", network, url)
+	}
+
+	if address == "" {
+		return "", "", false, usageErrorf("missing address in \"%s\"", url)
+	}
+
+	return network, address, stream, nil
+}
+
+// A connection to a target, for sending encoded packets.
+type target struct {
+	conn net.Conn
+
+	// Whether the transport is a stream, which needs packets to be framed.
+	stream bool
+
+	// Whether streams are framed with SLIP (OSC 1.1) rather than with a size
+	// prefix (OSC 1.0).
+	slip bool
+}
+
+// Connects to a target given as a URL (see parseURL).
+func dialTarget(url string, slip bool) (*target, error) {
+	network, address, stream, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &target{conn: conn, stream: stream, slip: slip}, nil
+}
+
+// Returns the bytes to write to send an encoded packet, including any framing.
+func (t *target) frame(packet []byte) ([]byte, error) {
+	if !t.stream {
+		return packet, nil
+	}
+
+	var out bytes.Buffer
+	if t.slip {
+		if err := gosc.NewSLIPWriter(&out).WriteFrame(packet); err != nil {
+			return nil, err
+		}
+	} else if _, err := gosc.WriteFramedPacket(&out, rawPacket(packet)); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// A packet that has already been encoded, e.g. one read from a recording,
+// which is sent exactly as it is.
+type rawPacket []byte
+
+func (p rawPacket) WriteTo(out io.Writer) (int, error) {
+	return out.Write(p)
+}
+
+func (p rawPacket) Valid() error {
+	return nil
+}
+
+// Sends data that has already been framed.
+func (t *target) write(framed []byte) error {
+	_, err := t.conn.Write(framed)
+	return err
+}
+
+func (t *target) Close() error {
+	return t.conn.Close()
+}
+
+// Listens on a URL (see parseURL; a bare ":port" means UDP) until the context
+// is done, calling handle with each packet received and where it came from.
+// Calls to handle are never concurrent, even with several stream
+// connections. Errors on individual connections are passed to onError.
+func listen(ctx context.Context, url string, slip bool, handle func(packet []byte, from net.Addr), onError func(err error, from net.Addr)) error {
+	network, address, stream, err := parseURL(url)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	serialized := func(packet []byte, from net.Addr) {
+		mu.Lock()
+		defer mu.Unlock()
+		handle(packet, from)
+	}
+
+	if !stream {
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+		if network == "unixgram" {
+			// Unlike a UnixListener, a datagram socket leaves its file behind.
+			defer os.Remove(address)
+		}
+		return listenPackets(ctx, conn, serialized)
+	}
+
+	// A UnixListener removes its socket file when closed.
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	return listenStreams(ctx, ln, slip, serialized, func(err error, from net.Addr) {
+		mu.Lock()
+		defer mu.Unlock()
+		onError(err, from)
+	})
+}
+
+func listenPackets(ctx context.Context, conn net.PacketConn, handle func([]byte, net.Addr)) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	buf := make([]byte, gosc.OSC_UDP_BUFFER_SIZE)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// Unix datagrams from an unbound socket have no address.
+		if from == nil || from.String() == "" {
+			from = conn.LocalAddr()
+		}
+
+		handle(buf[:n], from)
+	}
+}
+
+func listenStreams(ctx context.Context, ln net.Listener, slip bool, handle func([]byte, net.Addr), onError func(error, net.Addr)) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			conn.Close()
+		}
+	})
+	defer stop()
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+				conn.Close()
+			}()
+
+			// Unix socket clients usually have no address of their own.
+			from := conn.RemoteAddr()
+			if from == nil || from.String() == "" {
+				from = conn.LocalAddr()
+			}
+
+			err := readStream(conn, slip, func(packet []byte) {
+				handle(packet, from)
+			}, func(err error) {
+				onError(err, from)
+			})
+			if err != nil && ctx.Err() == nil {
+				onError(err, from)
+			}
+		}()
+	}
+}
+
+// Reads framed packets from a stream until it ends. A corrupt SLIP frame is
+// passed to onError and skipped, as the next END byte starts a new frame;
+// other errors end the stream.
+func readStream(in io.Reader, slip bool, handle func([]byte), onError func(error)) error {
+	if slip {
+		r := gosc.NewSLIPReader(in)
+		for {
+			if err := r.Next(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			frame, err := io.ReadAll(r)
+			var frameErr gosc.OSCReadError
+			if errors.As(err, &frameErr) {
+				onError(err)
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			handle(frame)
+		}
+	}
+
+	for {
+		frame, err := gosc.ReadFrame(in)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		handle(frame)
+	}
+}
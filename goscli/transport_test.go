@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	. "testing"
+	"testing/iotest"
+
+	"github.com/tokenshift/gosc"
+)
+
+// Frames packets with a size prefix, as for OSC 1.0 streams.
+func sizeFramed(packets ...[]byte) []byte {
+	var out bytes.Buffer
+	for _, p := range packets {
+		binary.Write(&out, binary.BigEndian, int32(len(p)))
+		out.Write(p)
+	}
+
+	return out.Bytes()
+}
+
+// Frames packets with SLIP, as for OSC 1.1 streams.
+func slipFramed(packets ...[]byte) []byte {
+	var out bytes.Buffer
+	w := gosc.NewSLIPWriter(&out)
+	for _, p := range packets {
+		w.WriteFrame(p)
+	}
+
+	return out.Bytes()
+}
+
+func readFrames(in io.Reader, slip bool) ([][]byte, []error, error) {
+	var frames [][]byte
+	var frameErrs []error
+	err := readStream(in, slip, func(frame []byte) {
+		// The frame's buffer is reused.
+		frames = append(frames, append([]byte{}, frame...))
+	}, func(err error) {
+		frameErrs = append(frameErrs, err)
+	})
+
+	return frames, frameErrs, err
+}
+
+func TestReadStream(t *T) {
+	a := []byte{47, 97, 0, 0, 44, 0, 0, 0}
+	b := []byte{47, 98, 0, 0, 44, 105, 0, 0, 0, 0, 0, 1}
+	special := []byte{0xc0, 0xdb, 1, 2}
+
+	inputs := map[string]struct {
+		data     []byte
+		slip     bool
+		expected [][]byte
+	}{
+		"size prefixed":     {sizeFramed(a, b, a), false, [][]byte{a, b, a}},
+		"empty frame":       {sizeFramed(a, []byte{}), false, [][]byte{a, {}}},
+		"empty stream":      {nil, false, nil},
+		"slip":              {slipFramed(a, b), true, [][]byte{a, b}},
+		"slip escapes":      {slipFramed(special), true, [][]byte{special}},
+		"slip empty stream": {nil, true, nil},
+	}
+
+	for name, input := range inputs {
+		// Streams arrive in pieces, so read a byte at a time.
+		frames, frameErrs, err := readFrames(iotest.OneByteReader(bytes.NewReader(input.data)), input.slip)
+		expectSame(t, 0, len(frameErrs))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else {
+			expectSame(t, input.expected, frames)
+		}
+	}
+}
+
+func TestReadStreamErrors(t *T) {
+	a := []byte{47, 97, 0, 0, 44, 0, 0, 0}
+	framed := sizeFramed(a)
+
+	inputs := map[string][]byte{
+		"truncated size":  framed[:2],
+		"truncated frame": framed[:len(framed) - 1],
+		"negative size":   {0xff, 0xff, 0xff, 0xf8},
+		"huge size":       {0x7f, 0xff, 0xff, 0xff},
+	}
+
+	for name, input := range inputs {
+		// Frames before the error are still delivered.
+		frames, _, err := readFrames(bytes.NewReader(append(sizeFramed(a), input...)), false)
+		if err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		expectSame(t, [][]byte{a}, frames)
+	}
+}
+
+func TestReadStreamCorruptSLIPFrame(t *T) {
+	a := []byte{47, 97, 0, 0, 44, 0, 0, 0}
+	b := []byte{47, 98, 0, 0, 44, 0, 0, 0}
+
+	// An invalid escape sequence spoils one frame, but the stream picks up
+	// again at the next END byte.
+	data := append(slipFramed(a), gosc.SLIP_END, 47, gosc.SLIP_ESC, 1, 2, gosc.SLIP_END)
+	data = append(data, slipFramed(b)...)
+
+	frames, frameErrs, err := readFrames(bytes.NewReader(data), true)
+	expectNil(t, err)
+	expectSame(t, [][]byte{a, b}, frames)
+	expectSame(t, 1, len(frameErrs))
+}
+
+func TestTargetFrame(t *T) {
+	a := []byte{47, 97, 0, 0, 44, 0, 0, 0}
+
+	inputs := map[string]struct {
+		target   target
+		expected []byte
+	}{
+		"datagram":      {target{}, a},
+		"size prefixed": {target{stream: true}, sizeFramed(a)},
+		"slip":          {target{stream: true, slip: true}, slipFramed(a)},
+	}
+
+	for name, input := range inputs {
+		framed, err := input.target.frame(a)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+		} else {
+			expectSame(t, input.expected, framed)
+		}
+	}
+
+	// Packets are framed as they are, even if they can't be decoded.
+	framed, err := (&target{stream: true}).frame([]byte{1, 2, 3})
+	expectNil(t, err)
+	expectSame(t, []byte{0, 0, 0, 3, 1, 2, 3}, framed)
+}
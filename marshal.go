@@ -0,0 +1,452 @@
+package gosc
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Marshal builds a message from the exported fields of a struct (or pointer
+// to a struct), one argument per field, in field order. Go types map to OSC
+// types as follows:
+//
+//	bool                     T or F
+//	int8, int16, int32,      i
+//	uint8, uint16
+//	int64                    h
+//	int, uint, uint32,       i if the value fits in an int32, otherwise h
+//	uint64
+//	float32                  f
+//	float64                  d
+//	string                   s
+//	[]byte                   b
+//	time.Time                t
+//	other slices and arrays  an array ([...]) of the elements
+//	pointers                 the value pointed to, or N if nil
+//	OSCArg                   the argument itself
+//
+// A field's struct tag can force a different OSC type, e.g. `osc:"f"` sends a
+// float64 field as a float32, and `osc:"i"` sends an int64 as an int32
+// (returning an error if it doesn't fit). `osc:",omit"` or `osc:"-"` skips
+// the field entirely.
+func Marshal(address OSCAddressPattern, v interface{}) (Message, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return Message{}, err
+	}
+
+	fields, err := marshalFields(rv.Type())
+	if err != nil {
+		return Message{}, err
+	}
+
+	m := Message{Address: address, Args: make([]OSCArg, 0, len(fields))}
+	for _, f := range fields {
+		arg, err := valueToArg(rv.Field(f.index), f.tag, false)
+		if err != nil {
+			return Message{}, OSCArgumentErrorf("%s: field %s: %w", address, f.name, err)
+		}
+
+		m.Args = append(m.Args, arg)
+	}
+
+	return m, m.Valid()
+}
+
+// Unmarshal stores the arguments of a message into the exported fields of the
+// struct pointed to by v, one argument per field, in field order. It is the
+// reverse of Marshal, and uses the same struct tags; integer and float fields
+// accept both the 32- and 64-bit OSC types, but values that don't fit in the
+// field are an error. The address is only used in error messages.
+func Unmarshal(address OSCAddressPattern, args []OSCArg, v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return OSCArgumentErrorf("Unmarshal requires a non-nil pointer to a struct, got %T", v)
+	}
+
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	fields, err := marshalFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	if len(args) != len(fields) {
+		return OSCArgumentErrorf("%s: expected %d arguments for %s, got %d", address, len(fields), rv.Type(), len(args))
+	}
+
+	for i, f := range fields {
+		if err := argToValue(args[i], rv.Field(f.index)); err != nil {
+			return OSCArgumentErrorf("%s: argument %d (field %s): %w", address, i, f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Dereferences v down to a struct value.
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return rv, OSCArgumentErrorf("expected a struct, got %T", v)
+	}
+
+	return rv, nil
+}
+
+// A struct field that maps to an argument.
+type marshalField struct {
+	index int
+	name  string
+	tag   OSCTypeTag // forced OSC type, or 0 for the default
+}
+
+// Lists the fields of a struct type that map to arguments, parsing their
+// struct tags.
+func marshalFields(t reflect.Type) ([]marshalField, error) {
+	var fields []marshalField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+
+		tag := sf.Tag.Get("osc")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma >= 0 {
+			name, opts = tag[:comma], tag[comma+1:]
+		}
+
+		if opts == "omit" {
+			continue
+		} else if opts != "" {
+			return nil, OSCArgumentErrorf("unknown option \"%s\" in osc tag of field %s", opts, sf.Name)
+		}
+
+		f := marshalField{index: i, name: sf.Name}
+		if len(name) == 1 {
+			f.tag = OSCTypeTag(name[0])
+		} else if len(name) > 1 {
+			return nil, OSCArgumentErrorf("invalid type \"%s\" in osc tag of field %s", name, sf.Name)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+var (
+	oscArgType = reflect.TypeOf((*OSCArg)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// Converts a Go value to an argument, optionally forcing a specific OSC type.
+// Int64 values are always sent as int64, unless fitInt64 is set, in which case
+// they're sent as int32 if they fit (as other integers are).
+func valueToArg(v reflect.Value, tag OSCTypeTag, fitInt64 bool) (OSCArg, error) {
+	if v.Type().Implements(oscArgType) && tag == 0 {
+		if v.Kind() == reflect.Interface && v.IsNil() {
+			return OSCNil{}, nil
+		}
+		return v.Interface().(OSCArg), nil
+	}
+
+	if v.Type() == timeType {
+		return forceType(OSCTimetagFromTime(v.Interface().(time.Time)), tag)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return forceType(OSCBool(v.Bool()), tag)
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return forceType(OSCInt32(v.Int()), tag)
+	case reflect.Int64:
+		if fitInt64 {
+			return forceType(intArg(v.Int()), tag)
+		}
+		return forceType(OSCInt64(v.Int()), tag)
+	case reflect.Int:
+		return forceType(intArg(v.Int()), tag)
+	case reflect.Uint8, reflect.Uint16:
+		return forceType(OSCInt32(v.Uint()), tag)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, OSCArgumentErrorf("%d overflows int64", v.Uint())
+		}
+		return forceType(intArg(int64(v.Uint())), tag)
+	case reflect.Float32:
+		return forceType(OSCFloat32(v.Float()), tag)
+	case reflect.Float64:
+		return forceType(OSCFloat64(v.Float()), tag)
+	case reflect.String:
+		return forceType(OSCString(v.String()), tag)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				return forceType(OSCBlob(v.Bytes()), tag)
+			}
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return forceType(OSCBlob(b), tag)
+		}
+
+		// The forced type, if any, applies to each element.
+		array := make(OSCArray, v.Len())
+		for i := range array {
+			elem, err := valueToArg(v.Index(i), tag, fitInt64)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = elem
+		}
+		return array, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return OSCNil{}, nil
+		}
+		return valueToArg(v.Elem(), tag, fitInt64)
+	}
+
+	return nil, OSCArgumentErrorf("unsupported type %s", v.Type())
+}
+
+// Chooses an int32 argument if the value fits, otherwise an int64.
+func intArg(i int64) OSCArg {
+	if i >= math.MinInt32 && i <= math.MaxInt32 {
+		return OSCInt32(i)
+	}
+
+	return OSCInt64(i)
+}
+
+// Converts an argument to the forced type, if there is one. Only conversions
+// between numeric types, and between the two string types, are supported.
+func forceType(arg OSCArg, tag OSCTypeTag) (OSCArg, error) {
+	if tag == 0 || tag == arg.Tag() {
+		return arg, nil
+	}
+
+	switch a := arg.(type) {
+	case OSCInt32:
+		return numberToTag(float64(a), int64(a), true, tag)
+	case OSCInt64:
+		return numberToTag(float64(a), int64(a), true, tag)
+	case OSCFloat32:
+		return numberToTag(float64(a), 0, false, tag)
+	case OSCFloat64:
+		return numberToTag(float64(a), 0, false, tag)
+	case OSCString:
+		if tag == OSC_ETYPE_STRING_ALT {
+			return OSCStringAlt(a), nil
+		}
+	case OSCBool:
+		// The value determines whether a bool is T or F, so either tag is fine.
+		if tag == OSC_ETYPE_TRUE || tag == OSC_ETYPE_FALSE {
+			return a, nil
+		}
+	}
+
+	return nil, OSCArgumentErrorf("can't send %s as '%c'", tagName(arg.Tag()), tag)
+}
+
+func numberToTag(f float64, i int64, isInt bool, tag OSCTypeTag) (OSCArg, error) {
+	switch tag {
+	case OSC_TYPE_FLOAT32:
+		return OSCFloat32(f), nil
+	case OSC_ETYPE_FLOAT64:
+		return OSCFloat64(f), nil
+	case OSC_TYPE_INT32:
+		if isInt && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return OSCInt32(i), nil
+		}
+		if isInt {
+			return nil, OSCArgumentErrorf("%d overflows int32", i)
+		}
+	case OSC_ETYPE_INT64:
+		if isInt {
+			return OSCInt64(i), nil
+		}
+	}
+
+	return nil, OSCArgumentErrorf("can't send a number as '%c'", tag)
+}
+
+// A readable name for a type tag, for error messages.
+func tagName(tag OSCTypeTag) string {
+	switch tag {
+	case OSC_TYPE_INT32:
+		return "int32"
+	case OSC_TYPE_FLOAT32:
+		return "float32"
+	case OSC_TYPE_STRING:
+		return "string"
+	case OSC_TYPE_BLOB:
+		return "blob"
+	case OSC_ETYPE_INT64:
+		return "int64"
+	case OSC_ETYPE_TIMETAG:
+		return "timetag"
+	case OSC_ETYPE_FLOAT64:
+		return "float64"
+	case OSC_ETYPE_STRING_ALT:
+		return "alternate string"
+	case OSC_ETYPE_CHAR:
+		return "char"
+	case OSC_ETYPE_RGBA:
+		return "RGBA color"
+	case OSC_ETYPE_MIDI:
+		return "MIDI message"
+	case OSC_ETYPE_TRUE, OSC_ETYPE_FALSE:
+		return "bool"
+	case OSC_ETYPE_NIL:
+		return "nil"
+	case OSC_ETYPE_INFINITY:
+		return "infinitum"
+	case OSC_ETYPE_ARRAY_START, OSC_ETYPE_ARRAY_END:
+		return "array"
+	default:
+		return "'" + string(rune(tag)) + "'"
+	}
+}
+
+// Stores an argument in a Go value, converting between compatible types.
+func argToValue(arg OSCArg, v reflect.Value) error {
+	if arg == nil {
+		return OSCArgumentErrorf("nil argument")
+	}
+
+	argValue := reflect.ValueOf(arg)
+
+	// OSCArg (and interface{}) fields take the argument as-is.
+	if v.Kind() == reflect.Interface || v.Type().Implements(oscArgType) {
+		if !argValue.Type().AssignableTo(v.Type()) {
+			return OSCArgumentErrorf("can't store %s in %s", tagName(arg.Tag()), v.Type())
+		}
+		v.Set(argValue)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		if t, ok := arg.(OSCTimetag); ok {
+			v.Set(reflect.ValueOf(t.Time()))
+			return nil
+		}
+		return OSCArgumentErrorf("can't store %s in %s", tagName(arg.Tag()), v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if _, ok := arg.(OSCNil); ok {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := argToValue(arg, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Bool:
+		if b, ok := arg.(OSCBool); ok {
+			v.SetBool(bool(b))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := argInt(arg); ok {
+			if v.OverflowInt(i) {
+				return OSCArgumentErrorf("%d overflows %s", i, v.Type())
+			}
+			v.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := argInt(arg); ok {
+			if i < 0 || v.OverflowUint(uint64(i)) {
+				return OSCArgumentErrorf("%d overflows %s", i, v.Type())
+			}
+			v.SetUint(uint64(i))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch a := arg.(type) {
+		case OSCFloat32:
+			v.SetFloat(float64(a))
+			return nil
+		case OSCFloat64:
+			v.SetFloat(float64(a))
+			return nil
+		}
+	case reflect.String:
+		switch a := arg.(type) {
+		case OSCString:
+			v.SetString(string(a))
+			return nil
+		case OSCStringAlt:
+			v.SetString(string(a))
+			return nil
+		}
+	case reflect.Slice:
+		if b, ok := arg.(OSCBlob); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte{}, b...))
+			return nil
+		}
+		if a, ok := arg.(OSCArray); ok {
+			s := reflect.MakeSlice(v.Type(), len(a), len(a))
+			for i, elem := range a {
+				if err := argToValue(elem, s.Index(i)); err != nil {
+					return OSCArgumentErrorf("element %d: %w", i, err)
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+	case reflect.Array:
+		// Fixed-size arrays only take arguments of exactly the right length.
+		if b, ok := arg.(OSCBlob); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			if len(b) != v.Len() {
+				return OSCArgumentErrorf("can't store a %d byte blob in %s", len(b), v.Type())
+			}
+			reflect.Copy(v, reflect.ValueOf([]byte(b)))
+			return nil
+		}
+		if a, ok := arg.(OSCArray); ok {
+			if len(a) != v.Len() {
+				return OSCArgumentErrorf("can't store an array of %d elements in %s", len(a), v.Type())
+			}
+			for i, elem := range a {
+				if err := argToValue(elem, v.Index(i)); err != nil {
+					return OSCArgumentErrorf("element %d: %w", i, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return OSCArgumentErrorf("can't store %s in %s", tagName(arg.Tag()), v.Type())
+}
+
+func argInt(arg OSCArg) (int64, bool) {
+	switch a := arg.(type) {
+	case OSCInt32:
+		return int64(a), true
+	case OSCInt64:
+		return int64(a), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,181 @@
+package gosc
+
+import (
+	. "testing"
+	"time"
+)
+
+type marshalTestFader struct {
+	Channel int32
+	Level   float64 `osc:"f"`
+	Label   string
+	Muted   bool
+	Data    []byte
+	Note    string `osc:",omit"`
+	Ignored int    `osc:"-"`
+	hidden  int
+}
+
+func TestMarshal(t *T) {
+	m, err := Marshal(OSCAddressPattern("/mixer/fader"), marshalTestFader{
+		Channel: 3,
+		Level:   0.5,
+		Label:   "Kick",
+		Muted:   true,
+		Data:    []byte{1, 2},
+		Note:    "not sent",
+	})
+	expectNil(t, err)
+
+	expectSame(t, Message{OSCAddressPattern("/mixer/fader"), []OSCArg{
+		OSCInt32(3), OSCFloat32(0.5), OSCString("Kick"), OSCBool(true), OSCBlob([]byte{1, 2}),
+	}}, m)
+}
+
+func TestMarshalDefaultTypes(t *T) {
+	var nilPtr *int32
+	seven := int32(7)
+
+	v := struct {
+		Small  int
+		Large  int
+		Wide   int64
+		Double float64
+		Alt    string `osc:"S"`
+		Narrow int64  `osc:"i"`
+		Time   time.Time
+		List   []int32
+		Nil    *int32
+		Ptr    *int32
+		Arg    OSCArg
+	}{
+		Small:  1,
+		Large:  1 << 40,
+		Wide:   2,
+		Double: 0.25,
+		Alt:    "alt",
+		Narrow: 5,
+		List:   []int32{1, 2},
+		Nil:    nilPtr,
+		Ptr:    &seven,
+		Arg:    OSCRGBA{1, 2, 3, 4},
+	}
+
+	m, err := Marshal(OSCAddressPattern("/types"), &v)
+	expectNil(t, err)
+
+	expectSame(t, []OSCArg{
+		OSCInt32(1), OSCInt64(1 << 40), OSCInt64(2), OSCFloat64(0.25), OSCStringAlt("alt"), OSCInt32(5),
+		OSC_TIMETAG_IMMEDIATELY, OSCArray{OSCInt32(1), OSCInt32(2)}, OSCNil{}, OSCInt32(7), OSCRGBA{1, 2, 3, 4},
+	}, m.Args)
+	expectSame(t, OSCString(",ihhdSit[ii]Nir"), m.TypeTags())
+}
+
+func TestMarshalErrors(t *T) {
+	_, err := Marshal(OSCAddressPattern("/a"), 5)
+	expectArgumentError(t, err, "expected a struct")
+
+	_, err = Marshal(OSCAddressPattern("/a"), struct{ C chan int }{})
+	expectArgumentError(t, err, "field C: unsupported type chan int")
+
+	_, err = Marshal(OSCAddressPattern("/a"), struct {
+		N int64 `osc:"i"`
+	}{1 << 40})
+	expectArgumentError(t, err, "overflows int32")
+
+	_, err = Marshal(OSCAddressPattern("/a"), struct {
+		S string `osc:"f"`
+	}{"x"})
+	expectArgumentError(t, err, "can't send string as 'f'")
+
+	_, err = Marshal(OSCAddressPattern("/a"), struct {
+		S string `osc:",bogus"`
+	}{})
+	expectArgumentError(t, err, "unknown option \"bogus\"")
+
+	_, err = Marshal(OSCAddressPattern("no slash"), struct{}{})
+	expectArgumentError(t, err, "")
+}
+
+func TestUnmarshal(t *T) {
+	var v marshalTestFader
+	v.Note = "unchanged"
+
+	err := Unmarshal(OSCAddressPattern("/mixer/fader"), []OSCArg{
+		OSCInt32(3), OSCFloat32(0.5), OSCString("Kick"), OSCBool(true), OSCBlob([]byte{1, 2}),
+	}, &v)
+	expectNil(t, err)
+
+	expectSame(t, marshalTestFader{
+		Channel: 3,
+		Level:   0.5,
+		Label:   "Kick",
+		Muted:   true,
+		Data:    []byte{1, 2},
+		Note:    "unchanged",
+	}, v)
+}
+
+func TestMarshalRoundTrip(t *T) {
+	type sample struct {
+		Count  uint16
+		Offset int64
+		Gain   float32
+		Name   string
+		When   time.Time
+		Values []float64
+		Opt    *string
+		Any    interface{}
+		ID     [4]byte
+		Pos    [3]float32
+	}
+
+	in := sample{
+		Count:  9,
+		Offset: -1 << 35,
+		Gain:   1.5,
+		Name:   "x",
+		When:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Values: []float64{1, 2.5},
+		Any:    OSCInfinity{},
+		ID:     [4]byte{1, 2, 3, 4},
+		Pos:    [3]float32{0.5, 1, -2},
+	}
+
+	m, err := Marshal(OSCAddressPattern("/sample"), in)
+	expectNil(t, err)
+
+	var out sample
+	expectNil(t, Unmarshal(m.Address, m.Args, &out))
+	expectSame(t, in, out)
+}
+
+func TestUnmarshalErrors(t *T) {
+	var v struct {
+		A int8
+		B string
+	}
+
+	err := Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCInt32(1)}, &v)
+	expectArgumentError(t, err, "/a: expected 2 arguments")
+
+	err = Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCInt32(300), OSCString("x")}, &v)
+	expectArgumentError(t, err, "argument 0 (field A): 300 overflows int8")
+
+	err = Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCInt32(1), OSCFloat32(2)}, &v)
+	expectArgumentError(t, err, "argument 1 (field B): can't store float32 in string")
+
+	err = Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCInt32(1), OSCString("x")}, v)
+	expectArgumentError(t, err, "non-nil pointer")
+
+	var arrays struct {
+		ID  [4]byte
+		Pos [2]int32
+	}
+
+	err = Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCBlob([]byte{1, 2}), OSCArray{OSCInt32(1), OSCInt32(2)}}, &arrays)
+	expectArgumentError(t, err, "argument 0 (field ID): can't store a 2 byte blob in [4]uint8")
+
+	err = Unmarshal(OSCAddressPattern("/a"), []OSCArg{OSCBlob([]byte{1, 2, 3, 4}), OSCArray{OSCInt32(1)}}, &arrays)
+	expectArgumentError(t, err, "argument 1 (field Pos): can't store an array of 1 elements in [2]int32")
+}
@@ -1,45 +1,76 @@
 package gosc
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
-// Writes an OSC message to the output stream. Returns an error if any of the
-// arguments were invalid, or if any transmission error occurred, and returns
-// the total number of bytes sent in either case.
-func WriteMessage(out io.Writer, address OSCAddressPattern, args...OSCArg) (int, error) {
-	if err := address.Valid(); err != nil {
-		return 0, err
+// A single OSC message: an address pattern and its arguments.
+type Message struct {
+	Address OSCAddressPattern
+	Args    []OSCArg
+}
+
+// Valid checks the address and all arguments of the message, returning the
+// first error found.
+func (m Message) Valid() error {
+	if err := m.Address.Valid(); err != nil {
+		return err
 	}
 
-	// Validate all of the arguments and construct the complete tag string
-	// before sending anything.
-	tagstring := make([]rune, len(args) + 1)
-	tagstring[0] = ','
-	for i, arg := range args {
+	for _, arg := range m.Args {
+		if arg == nil {
+			return OSCArgumentErrorf("nil argument")
+		}
+
 		if err := arg.Valid(); err != nil {
-			return 0, err
+			return err
 		}
+	}
 
-		tagstring[i+1] = rune(arg.Tag())
+	return nil
+}
+
+// TypeTags returns the tag string for the message's arguments, including the
+// leading comma (e.g. ",if[ss]").
+func (m Message) TypeTags() OSCString {
+	tags := make([]byte, 1, len(m.Args) + 1)
+	tags[0] = ','
+
+	for _, arg := range m.Args {
+		tags = appendTypeTags(tags, arg)
+	}
+
+	return OSCString(tags)
+}
+
+// WriteTo writes the message to the output stream. Returns an error if the
+// address or any of the arguments were invalid, or if any transmission error
+// occurred, and returns the total number of bytes sent in either case.
+func (m Message) WriteTo(out io.Writer) (int, error) {
+	// Validate everything before sending anything.
+	if err := m.Valid(); err != nil {
+		return 0, err
 	}
 
 	total := 0
 
-	if sent, err := address.WriteTo(out); err != nil {
+	if sent, err := m.Address.WriteTo(out); err != nil {
 		return sent, err
 	} else {
 		total += sent
 	}
 
-	if sent, err := OSCString(tagstring).WriteTo(out); err != nil {
-		return sent, err
+	if sent, err := m.TypeTags().WriteTo(out); err != nil {
+		return total+sent, err
 	} else {
 		total += sent
 	}
 
-	for _, arg := range args {
+	for _, arg := range m.Args {
 		if sent, err := arg.WriteTo(out); err != nil {
 			return total+sent, err
 		} else {
@@ -50,53 +81,263 @@ func WriteMessage(out io.Writer, address OSCAddressPattern, args...OSCArg) (int,
 	return total, nil
 }
 
+// MarshalBinary encodes the message as a complete OSC packet.
+func (m Message) MarshalBinary() ([]byte, error) {
+	var out bytes.Buffer
+	if _, err := m.WriteTo(&out); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a complete OSC packet into the message. The data
+// must contain exactly one message, with nothing left over.
+func (m *Message) UnmarshalBinary(data []byte) error {
+	in := bytes.NewReader(data)
+
+	msg, err := readMessage(in)
+	if err != nil {
+		return err
+	}
+
+	if in.Len() > 0 {
+		return OSCReadErrorf("%d bytes of unexpected data after message", in.Len())
+	}
+
+	*m = msg
+	return nil
+}
+
+// String formats the message for display, as the address followed by the tag
+// string and each of the arguments, e.g. `/mixer/1/label ,sf "Kick" 0.5`.
+func (m Message) String() string {
+	var out strings.Builder
+
+	out.WriteString(string(m.Address))
+	out.WriteByte(' ')
+	out.WriteString(string(m.TypeTags()))
+
+	for _, arg := range m.Args {
+		out.WriteByte(' ')
+		out.WriteString(FormatArg(arg))
+	}
+
+	return out.String()
+}
+
+// FormatArg formats a single argument for display. Strings are quoted, blobs
+// are shown in hex, and arrays are shown in brackets.
+func FormatArg(arg OSCArg) string {
+	switch a := arg.(type) {
+	case OSCString:
+		return fmt.Sprintf("%q", string(a))
+	case OSCStringAlt:
+		return fmt.Sprintf("%q", string(a))
+	case OSCBlob:
+		return fmt.Sprintf("0x%x", []byte(a))
+	case OSCChar:
+		return fmt.Sprintf("%q", rune(a))
+	case OSCRGBA:
+		return fmt.Sprintf("#%02x%02x%02x%02x", a.R, a.G, a.B, a.A)
+	case OSCMIDI:
+		return fmt.Sprintf("midi(%d %d %d %d)", a.Port, a.Status, a.Data1, a.Data2)
+	case OSCTimetag:
+		if a.IsImmediate() {
+			return "immediately"
+		}
+		return a.Time().Format(time.RFC3339Nano)
+	case OSCNil:
+		return "nil"
+	case OSCInfinity:
+		return "inf"
+	case OSCArray:
+		elems := make([]string, len(a))
+		for i, elem := range a {
+			elems[i] = FormatArg(elem)
+		}
+		return "[" + strings.Join(elems, " ") + "]"
+	case nil:
+		return "<nil>"
+	default:
+		return fmt.Sprintf("%v", a)
+	}
+}
+
+// Writes an OSC message to the output stream. Returns an error if any of the
+// arguments were invalid, or if any transmission error occurred, and returns
+// the total number of bytes sent in either case.
+func WriteMessage(out io.Writer, address OSCAddressPattern, args...OSCArg) (int, error) {
+	return Message{address, args}.WriteTo(out)
+}
+
 // Reads an OSC message from an input stream, returning the address and
 // arguments, or an error if the message could not be read successfully.
 func ReadMessage(in io.Reader) (OSCAddressPattern, []OSCArg, error) {
-	address, err := ReadOSCString(in)
+	m, err := readMessage(in)
+	return m.Address, m.Args, err
+}
+
+func readMessage(in io.Reader) (Message, error) {
+	return readMessageWith(in, DecodeOptions{}.effective())
+}
+
+func readMessageWith(in io.Reader, opts DecodeOptions) (Message, error) {
+	address, err := readOSCString(in, opts)
 	if err != nil {
-		return "", nil, err
+		return Message{}, annotateError(err, 0, -1, 0)
+	}
+
+	return readMessageBody(in, address, opts)
+}
+
+// Reads the remainder of an OSC message (the tag string and arguments), once
+// the address has already been read from the input.
+func readMessageBody(in io.Reader, address OSCString, opts DecodeOptions) (Message, error) {
+	m := Message{Address: OSCAddressPattern(address)}
+	if err := m.Address.Valid(); err != nil {
+		return Message{}, err
 	}
 
-	oaddress := OSCAddressPattern(address)
-	if err = oaddress.Valid(); err != nil {
-		return "", nil, err
+	d := &decoder{
+		in:   in,
+		n:    (len(address) + OSC_BYTE_ALIGNMENT) / OSC_BYTE_ALIGNMENT * OSC_BYTE_ALIGNMENT,
+		opts: opts,
 	}
+	tagsAt := d.n
 
-	tagString, err := ReadOSCString(in)
+	tagString, raw, err := d.tagString()
 	if err != nil {
-		return oaddress, nil, err
-	}
-	if !strings.HasPrefix(string(tagString), ",") {
-		return oaddress, nil, OSCReadErrorf("tag string (%s) must start with a comma", tagString)
-	}
-	if err = tagString.Valid(); err != nil {
-		return oaddress, nil, err
-	}
-
-	args := make([]OSCArg, 0, len(tagString) - 1)
-	for _, tag := range tagString[1:] {
-		var arg OSCArg
-
-		switch OSCTypeTag(tag) {
-		case OSC_TYPE_INT32:
-			arg, err = ReadOSCInt32(in)
-		case OSC_TYPE_FLOAT32:
-			arg, err = ReadOSCFloat32(in)
-		case OSC_TYPE_STRING:
-			arg, err = ReadOSCString(in)
-		case OSC_TYPE_BLOB:
-			arg, err = ReadOSCBlob(in)
-		default:
-			return oaddress, nil, OSCReadErrorf("unsupported type tag: '%s'", string(tag))
+		return m, annotateError(err, tagsAt, -1, 0)
+	}
+	if raw != nil {
+		m.Args = raw
+		return m, nil
+	}
+	if !strings.HasPrefix(tagString, ",") {
+		return m, annotateError(OSCReadErrorf("tag string (%s) must start with a comma", tagString), tagsAt, -1, 0)
+	}
+	if err = OSCString(tagString).Valid(); err != nil {
+		return m, err
+	}
+
+	m.Args, _, err = d.args(tagString, 1, 0)
+	return m, err
+}
+
+// Reads the arguments described by the tag string, starting at position
+// start and continuing until the end of the tag string or (inside an array)
+// the matching closing bracket. Returns the arguments and the position of the
+// last tag consumed.
+func (d *decoder) args(tags string, start, depth int) ([]OSCArg, int, error) {
+	args := make([]OSCArg, 0, len(tags) - start)
+
+	for i := start; i < len(tags); i++ {
+		tag := OSCTypeTag(tags[i])
+		offset := d.n
+
+		// Errors are reported against the top-level argument they occurred
+		// in, but with the offset and tag of the innermost one.
+		index := -1
+		if depth == 0 {
+			index = len(args)
 		}
 
+		switch tag {
+		case OSC_ETYPE_ARRAY_START:
+			elems, end, err := d.args(tags, i+1, depth+1)
+			if err != nil {
+				return args, end, annotateError(err, offset, index, tag)
+			}
+			args = append(args, OSCArray(elems))
+			if d.stopped {
+				return args, len(tags), nil
+			}
+			i = end
+			continue
+		case OSC_ETYPE_ARRAY_END:
+			if depth == 0 {
+				return args, i, OSCReadErrorf("unmatched ']' at position %d in tag string \"%s\"", i, tags)
+			}
+			return args, i, nil
+		}
+
+		arg, err := d.arg(tag)
+		if e, ok := err.(OSCReadError); ok && e.Kind == ErrUnknownTag && d.opts.UnknownTagPolicy != UnknownTagError {
+			rest, err := d.unknown(tags, i)
+			if err != nil {
+				return args, i, annotateError(err, offset, index, tag)
+			}
+			return append(args, rest...), len(tags), nil
+		}
 		if err != nil {
-			return oaddress, args, err
+			return args, i, annotateError(err, offset, index, tag)
 		}
 
 		args = append(args, arg)
 	}
 
-	return oaddress, args, nil
+	if depth > 0 {
+		return args, len(tags), OSCReadErrorf("unterminated '[' in tag string \"%s\"", tags)
+	}
+
+	return args, len(tags), nil
+}
+
+// Reads a single (non-array) argument, applying the decoding options to
+// strings and looking up custom types in the registry.
+func (d *decoder) arg(tag OSCTypeTag) (OSCArg, error) {
+	switch tag {
+	case OSC_TYPE_STRING:
+		return readOSCString(d, d.opts)
+	case OSC_ETYPE_STRING_ALT:
+		s, err := readOSCString(d, d.opts)
+		return OSCStringAlt(s), err
+	case OSC_TYPE_BLOB:
+		return ReadOSCBlobLimit(d, d.opts.MaxBlobSize)
+	}
+
+	if !isBuiltinTag(tag) {
+		if decoder, ok := d.opts.Types.Lookup(tag); ok {
+			return decodeCustom(d, func() int { return d.n }, tag, decoder)
+		}
+	}
+
+	return readArg(d, tag)
+}
+
+// Reads a single (non-array) argument of the specified type.
+func readArg(in io.Reader, tag OSCTypeTag) (OSCArg, error) {
+	switch tag {
+	case OSC_TYPE_INT32:
+		return ReadOSCInt32(in)
+	case OSC_TYPE_FLOAT32:
+		return ReadOSCFloat32(in)
+	case OSC_TYPE_STRING:
+		return ReadOSCString(in)
+	case OSC_TYPE_BLOB:
+		return ReadOSCBlob(in)
+	case OSC_ETYPE_INT64:
+		return ReadOSCInt64(in)
+	case OSC_ETYPE_FLOAT64:
+		return ReadOSCFloat64(in)
+	case OSC_ETYPE_TIMETAG:
+		return ReadOSCTimetag(in)
+	case OSC_ETYPE_STRING_ALT:
+		return ReadOSCStringAlt(in)
+	case OSC_ETYPE_CHAR:
+		return ReadOSCChar(in)
+	case OSC_ETYPE_RGBA:
+		return ReadOSCRGBA(in)
+	case OSC_ETYPE_MIDI:
+		return ReadOSCMIDI(in)
+	case OSC_ETYPE_TRUE, OSC_ETYPE_FALSE:
+		return ReadOSCBool(in, tag)
+	case OSC_ETYPE_NIL:
+		return ReadOSCNil(in)
+	case OSC_ETYPE_INFINITY:
+		return ReadOSCInfinity(in)
+	default:
+		return nil, OSCReadErrorf("unsupported type tag: '%c'", tag).withKind(ErrUnknownTag)
+	}
 }
@@ -132,3 +132,160 @@ func TestReadMessageStandardTypes(t *T) {
 		OSCBlob([]byte{1,2,3,4,5}),
 	}, args)
 }
+
+func TestMessageExtendedTypesRoundTrip(t *T) {
+	var out bytes.Buffer
+
+	args := []OSCArg{
+		OSCInt64(-1),
+		OSCFloat64(0.125),
+		OSCTimetag(1),
+		OSCStringAlt("sym"),
+		OSCChar('c'),
+		OSCRGBA{1, 2, 3, 4},
+		OSCMIDI{1, 0x80, 64, 0},
+		OSCBool(true),
+		OSCBool(false),
+		OSCNil{},
+		OSCInfinity{},
+		OSCInt32(7),
+	}
+
+	n, err := WriteMessage(&out, OSCAddressPattern("/ext"), args...)
+	expectNil(t, err)
+	expectSame(t, 8+16+8+8+8+4+4+4+4+4, n)
+	expectSame(t, []byte(",hdtScrmTFNIi\x00\x00\x00"), out.Bytes()[8:24])
+
+	address, read, err := ReadMessage(bytes.NewReader(out.Bytes()))
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/ext"), address)
+	expectSame(t, args, read)
+}
+
+func TestWriteMessageArray(t *T) {
+	var out bytes.Buffer
+
+	n, err := WriteMessage(&out, OSCAddressPattern("/a"),
+		OSCInt32(1), OSCArray{OSCInt32(2), OSCArray{}, OSCString("x")})
+	expectNil(t, err)
+	expectSame(t, 4+12+12, n)
+	expectSame(t,
+		[]byte{47,97,0,0,                // "/a"
+		       44,105,91,105,91,93,115,93,0,0,0,0, // ",i[i[]s]"
+		       0,0,0,1,
+		       0,0,0,2,
+		       120,0,0,0},
+		out.Bytes())
+}
+
+func TestMessageArrayRoundTrip(t *T) {
+	var out bytes.Buffer
+
+	args := []OSCArg{
+		OSCArray{},
+		OSCArray{OSCArray{OSCArray{OSCInt32(3)}}, OSCFloat32(1.5)},
+		OSCString("after"),
+		OSCArray{OSCNil{}, OSCBlob([]byte{1,2,3,4})},
+	}
+
+	_, err := WriteMessage(&out, OSCAddressPattern("/nested"), args...)
+	expectNil(t, err)
+
+	address, read, err := ReadMessage(bytes.NewReader(out.Bytes()))
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/nested"), address)
+	expectSame(t, args, read)
+}
+
+func TestReadMessageMismatchedBrackets(t *T) {
+	for _, tags := range []string{",[i", ",i]", ",[[]", ",]["} {
+		var in bytes.Buffer
+		OSCString("/a").WriteTo(&in)
+		OSCString(tags).WriteTo(&in)
+		OSCInt32(1).WriteTo(&in)
+
+		_, _, err := ReadMessage(&in)
+		if _, ok := err.(OSCReadError); !ok {
+			t.Errorf("expected an OSCReadError for tag string %q, got %#v", tags, err)
+		}
+	}
+}
+
+func TestMessageTypeTags(t *T) {
+	expectSame(t, OSCString(","), Message{OSCAddressPattern("/a"), nil}.TypeTags())
+	expectSame(t, OSCString(",if[sT]N"), Message{OSCAddressPattern("/a"), []OSCArg{
+		OSCInt32(1), OSCFloat32(2), OSCArray{OSCString("x"), OSCBool(true)}, OSCNil{},
+	}}.TypeTags())
+}
+
+func TestMessageMarshalBinary(t *T) {
+	m := Message{OSCAddressPattern("/something"), []OSCArg{OSCString("foo")}}
+
+	data, err := m.MarshalBinary()
+	expectNil(t, err)
+	expectSame(t,
+		[]byte{47,115,111,109,101,116,104,105,110,103,0,0, // "/something" (address)
+		       44,115,0,0,                                 // ",s" (type string)
+		       102,111,111,0},                             // "foo"
+		data)
+
+	var read Message
+	expectNil(t, read.UnmarshalBinary(data))
+	expectSame(t, m, read)
+
+	_, err = Message{OSCAddressPattern("no slash"), nil}.MarshalBinary()
+	if _, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError, got %#v", err)
+	}
+}
+
+func TestMessageUnmarshalBinaryTrailingData(t *T) {
+	data, err := Message{OSCAddressPattern("/a"), nil}.MarshalBinary()
+	expectNil(t, err)
+
+	var read Message
+	if _, ok := read.UnmarshalBinary(append(data, 0, 0, 0, 0)).(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError for trailing data")
+	}
+	expectSame(t, Message{}, read)
+}
+
+func TestMessageString(t *T) {
+	m := Message{OSCAddressPattern("/mixer/1/label"), []OSCArg{
+		OSCString("Kick"),
+		OSCFloat32(0.5),
+		OSCBlob([]byte{1, 0xab}),
+		OSCArray{OSCInt32(1), OSCBool(false)},
+		OSCNil{},
+		OSC_TIMETAG_IMMEDIATELY,
+	}}
+
+	expectSame(t, `/mixer/1/label ,sfb[iF]Nt "Kick" 0.5 0x01ab [1 false] nil immediately`, m.String())
+}
+
+func TestWriteMessageMatchesMessageWriteTo(t *T) {
+	var a, b bytes.Buffer
+
+	args := []OSCArg{OSCInt32(1), OSCString("two"), OSCBlob([]byte{3})}
+
+	n1, err := WriteMessage(&a, OSCAddressPattern("/x"), args...)
+	expectNil(t, err)
+	n2, err := Message{OSCAddressPattern("/x"), args}.WriteTo(&b)
+	expectNil(t, err)
+
+	expectSame(t, n1, n2)
+	expectSame(t, a.Bytes(), b.Bytes())
+}
+
+func TestMessageUnalignedBlobRoundTrip(t *T) {
+	var out bytes.Buffer
+
+	args := []OSCArg{OSCBlob([]byte{1,2,3}), OSCInt32(4), OSCBlob([]byte{5}), OSCString("six")}
+
+	_, err := WriteMessage(&out, OSCAddressPattern("/blobs"), args...)
+	expectNil(t, err)
+
+	_, read, err := ReadMessage(&out)
+	expectNil(t, err)
+	expectSame(t, args, read)
+}
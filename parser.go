@@ -0,0 +1,363 @@
+package gosc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// ParseMessage decodes a complete message from a byte slice (such as a UDP
+// datagram) without copying its binary data. Strings are copied (unless
+// ParseMessageNoCopy is used), but blobs (and raw data, see OSCRaw) in the
+// result point directly into data, so data must not be modified or reused
+// while they are in use; call Copy on the result to detach it from data.
+//
+// The data must contain exactly one message, with nothing left over.
+func ParseMessage(data []byte) (Message, error) {
+	return ParseMessageWith(data, DecodeOptions{})
+}
+
+// ParseMessageWith is like ParseMessage, but with options that relax the
+// rules for decoding (see ReadMessageWith).
+func ParseMessageWith(data []byte, opts DecodeOptions) (Message, error) {
+	return parseMessage(data, opts, false)
+}
+
+// ParseMessageNoCopy is like ParseMessage, but doesn't copy strings either, so
+// that decoding a message allocates as little as possible.
+//
+// This is unsafe: the address and string arguments share memory with data,
+// and Go strings are assumed never to change, so modifying or reusing data
+// while they are in use (including as map keys) silently changes them. Call
+// Copy on the result before data is reused.
+func ParseMessageNoCopy(data []byte) (Message, error) {
+	return parseMessage(data, DecodeOptions{}, true)
+}
+
+// ParseMessageNoCopyWith is like ParseMessageNoCopy, but with options that
+// relax the rules for decoding (see ReadMessageWith).
+func ParseMessageNoCopyWith(data []byte, opts DecodeOptions) (Message, error) {
+	return parseMessage(data, opts, true)
+}
+
+func parseMessage(data []byte, opts DecodeOptions, noCopy bool) (Message, error) {
+	p := parser{data: data, opts: opts.effective(), noCopy: noCopy}
+
+	address, err := p.string()
+	if err != nil {
+		return Message{}, annotateError(err, 0, -1, 0)
+	}
+
+	m := Message{Address: OSCAddressPattern(address)}
+	if err := m.Address.Valid(); err != nil {
+		return Message{}, err
+	}
+
+	tagsAt := p.pos
+	if p.opts.AllowMissingTagString && (p.remaining() == 0 || p.data[p.pos] != ',') {
+		// No tag string, so the arguments can't be decoded.
+		m.Args = []OSCArg{}
+		if p.remaining() > 0 {
+			m.Args = append(m.Args, OSCRaw{Data: p.data[p.pos:len(p.data):len(p.data)]})
+		}
+		return m, nil
+	}
+
+	tagString, err := p.string()
+	if err != nil {
+		return m, annotateError(err, tagsAt, -1, 0)
+	}
+	if !strings.HasPrefix(tagString, ",") {
+		return m, annotateError(OSCReadErrorf("tag string (%s) must start with a comma", tagString), tagsAt, -1, 0)
+	}
+
+	m.Args, _, err = p.args(tagString, 1, 0)
+	if err != nil {
+		return m, err
+	}
+
+	if p.pos != len(data) {
+		return m, OSCReadErrorf("%d bytes of unexpected data after message", len(data) - p.pos)
+	}
+
+	return m, nil
+}
+
+// Copy returns a deep copy of the message, with its own copies of all strings
+// and blobs. Use it to keep a message returned by ParseMessage after the
+// underlying buffer has been reused.
+func (m Message) Copy() Message {
+	return Message{
+		Address: OSCAddressPattern(strings.Clone(string(m.Address))),
+		Args:    copyArgs(m.Args),
+	}
+}
+
+func copyArgs(args []OSCArg) []OSCArg {
+	if args == nil {
+		return nil
+	}
+
+	out := make([]OSCArg, len(args))
+	for i, arg := range args {
+		switch a := arg.(type) {
+		case OSCString:
+			out[i] = OSCString(strings.Clone(string(a)))
+		case OSCStringAlt:
+			out[i] = OSCStringAlt(strings.Clone(string(a)))
+		case OSCBlob:
+			out[i] = OSCBlob(bytes.Clone(a))
+		case OSCArray:
+			out[i] = OSCArray(copyArgs(a))
+		case OSCRaw:
+			out[i] = OSCRaw{Tags: strings.Clone(a.Tags), Data: bytes.Clone(a.Data)}
+		default:
+			out[i] = arg
+		}
+	}
+
+	return out
+}
+
+// Walks a packet in memory, in the same way that the Read* functions walk an
+// input stream.
+type parser struct {
+	data []byte
+	pos  int
+	opts DecodeOptions
+
+	// Whether strings point into data instead of being copied.
+	noCopy bool
+
+	// Set once an unknown tag has ended parsing early.
+	stopped bool
+}
+
+func (p *parser) remaining() int {
+	return len(p.data) - p.pos
+}
+
+// Returns the next OSC-string. Unless noCopy is set, the string is copied so
+// that it stays valid, e.g. as a map key, when the data is reused.
+func (p *parser) string() (string, error) {
+	rest := p.data[p.pos:]
+
+	end := bytes.IndexByte(rest, 0)
+	if end < 0 {
+		return "", OSCReadErrorf("reached end of input before null terminator").withKind(ErrTruncated)
+	}
+
+	padded := (end + OSC_BYTE_ALIGNMENT) / OSC_BYTE_ALIGNMENT * OSC_BYTE_ALIGNMENT
+	if padded > len(rest) {
+		if !p.opts.AllowUnpaddedTail {
+			return "", OSCReadErrorf("reached end of input before OSC-string padding").withKind(ErrTruncated)
+		}
+		padded = len(rest)
+	}
+
+	for _, b := range rest[end:padded] {
+		if b != 0 {
+			return "", OSCReadErrorf("OSC-string was not padded properly").withKind(ErrBadPadding)
+		}
+	}
+
+	var s string
+	if p.noCopy && end > 0 {
+		s = unsafe.String(&rest[0], end)
+	} else {
+		s = string(rest[:end])
+	}
+
+	if p.opts.AllowUTF8Strings {
+		if !utf8.ValidString(s) {
+			return "", OSCReadErrorf("invalid UTF-8 in string \"%s\"", s)
+		}
+	} else if err := OSCString(s).Valid(); err != nil {
+		return "", err
+	}
+
+	p.pos += padded
+	return s, nil
+}
+
+func (p *parser) uint32(what string) (uint32, error) {
+	if p.remaining() < 4 {
+		return 0, OSCReadErrorf("failed to read %s: unexpected end of input", what).withKind(ErrTruncated)
+	}
+
+	v := binary.BigEndian.Uint32(p.data[p.pos:])
+	p.pos += 4
+	return v, nil
+}
+
+func (p *parser) uint64(what string) (uint64, error) {
+	if p.remaining() < 8 {
+		return 0, OSCReadErrorf("failed to read %s: unexpected end of input", what).withKind(ErrTruncated)
+	}
+
+	v := binary.BigEndian.Uint64(p.data[p.pos:])
+	p.pos += 8
+	return v, nil
+}
+
+// Returns the next blob, pointing into the data.
+func (p *parser) blob() (OSCBlob, error) {
+	v, err := p.uint32("blob size")
+	if err != nil {
+		return nil, err
+	}
+
+	size := int32(v)
+	if size < 0 {
+		return nil, OSCReadErrorf("invalid blob size %d", size)
+	}
+
+	if int(size) > p.opts.MaxBlobSize {
+		return nil, OSCReadErrorf("blob size %d exceeds maximum of %d bytes", size, p.opts.MaxBlobSize).withKind(ErrTooLarge)
+	}
+
+	padded := (int(size) + OSC_BYTE_ALIGNMENT - 1) / OSC_BYTE_ALIGNMENT * OSC_BYTE_ALIGNMENT
+	if padded > p.remaining() {
+		return nil, OSCReadErrorf("failed to read complete blob, got %d bytes out of %d", p.remaining(), padded).withKind(ErrTruncated)
+	}
+
+	data := p.data[p.pos:p.pos+padded]
+	for _, b := range data[size:] {
+		if b != 0 {
+			return nil, OSCReadErrorf("blob was not padded properly").withKind(ErrBadPadding)
+		}
+	}
+
+	p.pos += padded
+	return OSCBlob(data[:size:size]), nil
+}
+
+// Parses the arguments described by the tag string; see readArgs.
+func (p *parser) args(tags string, start, depth int) ([]OSCArg, int, error) {
+	args := make([]OSCArg, 0, len(tags) - start)
+
+	for i := start; i < len(tags); i++ {
+		tag := OSCTypeTag(tags[i])
+		offset := p.pos
+
+		index := -1
+		if depth == 0 {
+			index = len(args)
+		}
+
+		switch tag {
+		case OSC_ETYPE_ARRAY_START:
+			elems, end, err := p.args(tags, i+1, depth+1)
+			if err != nil {
+				return args, end, annotateError(err, offset, index, tag)
+			}
+			args = append(args, OSCArray(elems))
+			if p.stopped {
+				return args, len(tags), nil
+			}
+			i = end
+			continue
+		case OSC_ETYPE_ARRAY_END:
+			if depth == 0 {
+				return args, i, OSCReadErrorf("unmatched ']' at position %d in tag string \"%s\"", i, tags)
+			}
+			return args, i, nil
+		}
+
+		arg, err := p.arg(tag)
+		if e, ok := err.(OSCReadError); ok && e.Kind == ErrUnknownTag && p.opts.UnknownTagPolicy != UnknownTagError {
+			return append(args, p.unknown(tags, i)...), len(tags), nil
+		}
+		if err != nil {
+			return args, i, annotateError(err, offset, index, tag)
+		}
+
+		args = append(args, arg)
+	}
+
+	if depth > 0 {
+		return args, len(tags), OSCReadErrorf("unterminated '[' in tag string \"%s\"", tags)
+	}
+
+	return args, len(tags), nil
+}
+
+// Handles an unknown type tag at position i in the tag string, according to
+// the UnknownTagPolicy (see decoder.unknown).
+func (p *parser) unknown(tags string, i int) []OSCArg {
+	data := p.data[p.pos:len(p.data):len(p.data)]
+	p.pos = len(p.data)
+	p.stopped = true
+
+	if p.opts.UnknownTagPolicy == UnknownTagRaw {
+		return []OSCArg{OSCRaw{Tags: tags[i:], Data: data}}
+	}
+
+	return nil
+}
+
+// Parses a single (non-array) argument of the specified type.
+func (p *parser) arg(tag OSCTypeTag) (OSCArg, error) {
+	switch tag {
+	case OSC_TYPE_INT32:
+		v, err := p.uint32("int32")
+		return OSCInt32(v), err
+	case OSC_TYPE_FLOAT32:
+		v, err := p.uint32("float32")
+		return OSCFloat32(math.Float32frombits(v)), err
+	case OSC_TYPE_STRING:
+		s, err := p.string()
+		return OSCString(s), err
+	case OSC_TYPE_BLOB:
+		return p.blob()
+	case OSC_ETYPE_INT64:
+		v, err := p.uint64("int64")
+		return OSCInt64(v), err
+	case OSC_ETYPE_FLOAT64:
+		v, err := p.uint64("float64")
+		return OSCFloat64(math.Float64frombits(v)), err
+	case OSC_ETYPE_TIMETAG:
+		v, err := p.uint64("timetag")
+		return OSCTimetag(v), err
+	case OSC_ETYPE_STRING_ALT:
+		s, err := p.string()
+		return OSCStringAlt(s), err
+	case OSC_ETYPE_CHAR:
+		v, err := p.uint32("char")
+		if err != nil {
+			return nil, err
+		}
+		c := OSCChar(v)
+		return c, c.Valid()
+	case OSC_ETYPE_RGBA:
+		v, err := p.uint32("RGBA color")
+		return OSCRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, err
+	case OSC_ETYPE_MIDI:
+		v, err := p.uint32("MIDI message")
+		return OSCMIDI{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, err
+	case OSC_ETYPE_TRUE:
+		return OSCBool(true), nil
+	case OSC_ETYPE_FALSE:
+		return OSCBool(false), nil
+	case OSC_ETYPE_NIL:
+		return OSCNil{}, nil
+	case OSC_ETYPE_INFINITY:
+		return OSCInfinity{}, nil
+	default:
+		decoder, ok := p.opts.Types.Lookup(tag)
+		if !ok {
+			return nil, OSCReadErrorf("unsupported type tag: '%c'", tag).withKind(ErrUnknownTag)
+		}
+
+		// Custom decoders read from a stream, so they can't avoid copying.
+		in := bytes.NewReader(p.data[p.pos:])
+		arg, err := decodeCustom(in, func() int { return len(p.data) - p.pos - in.Len() }, tag, decoder)
+		if err == nil {
+			p.pos = len(p.data) - in.Len()
+		}
+		return arg, err
+	}
+}
@@ -0,0 +1,202 @@
+package gosc
+
+import (
+	"bytes"
+	"errors"
+	. "testing"
+)
+
+func TestParseMessageMatchesReadMessage(t *T) {
+	data, err := AppendMessage(nil, OSCAddressPattern("/send/this/here"), encoderTestArgs...)
+	expectNil(t, err)
+
+	address, args, err := ReadMessage(bytes.NewReader(data))
+	expectNil(t, err)
+
+	m, err := ParseMessage(data)
+	expectNil(t, err)
+	expectSame(t, Message{address, args}, m)
+}
+
+func TestParseMessageZeroCopy(t *T) {
+	data, err := AppendMessage(nil, OSCAddressPattern("/a"), OSCBlob([]byte{1,2,3}), OSCString("abc"))
+	expectNil(t, err)
+
+	m, err := ParseMessage(data)
+	expectNil(t, err)
+
+	// The blob points into the original buffer...
+	blob := m.Args[0].(OSCBlob)
+	expectSame(t, &data[12], &blob[0])
+	expectSame(t, 3, cap(blob))
+
+	// ...unless it has been copied.
+	copied := m.Copy()
+	expectSame(t, m, copied)
+
+	data[12] = 9
+	expectSame(t, OSCBlob([]byte{9,2,3}), m.Args[0])
+	expectSame(t, OSCBlob([]byte{1,2,3}), copied.Args[0])
+}
+
+func TestParseMessageCopiesStrings(t *T) {
+	data, err := AppendMessage(nil, OSCAddressPattern("/a"), OSCString("abc"))
+	expectNil(t, err)
+
+	m, err := ParseMessage(data)
+	expectNil(t, err)
+
+	// Reusing the buffer doesn't change strings already parsed from it.
+	copy(data, []byte("/b\x00\x00,s\x00\x00xyz"))
+	expectSame(t, Message{OSCAddressPattern("/a"), []OSCArg{OSCString("abc")}}, m)
+}
+
+func TestParseMessageNoCopy(t *T) {
+	data, err := AppendMessage(nil, OSCAddressPattern("/a"), OSCString("abc"), OSCStringAlt(""))
+	expectNil(t, err)
+
+	m, err := ParseMessageNoCopy(data)
+	expectNil(t, err)
+	expectSame(t, Message{OSCAddressPattern("/a"), []OSCArg{OSCString("abc"), OSCStringAlt("")}}, m)
+
+	copied := m.Copy()
+
+	// Strings point into the buffer, so reusing it changes them.
+	copy(data, []byte("/b\x00\x00,sS\x00xyz"))
+	expectSame(t, OSCAddressPattern("/b"), m.Address)
+	expectSame(t, OSCString("xyz"), m.Args[0])
+	expectSame(t, Message{OSCAddressPattern("/a"), []OSCArg{OSCString("abc"), OSCStringAlt("")}}, copied)
+
+	// Not copying saves an allocation each for the address, the tag string
+	// and the string argument.
+	copying := AllocsPerRun(100, func() { ParseMessage(benchmarkPacket) })
+	allocs := AllocsPerRun(100, func() { ParseMessageNoCopy(benchmarkPacket) })
+	expectSame(t, copying - 3, allocs)
+}
+
+func TestParseMessageWithMatchesReadMessageWith(t *T) {
+	lenient := DecodeOptions{
+		AllowMissingTagString: true,
+		AllowUTF8Strings:      true,
+		AllowUnpaddedTail:     true,
+		UnknownTagPolicy:      UnknownTagRaw,
+	}
+
+	inputs := [][]byte{
+		{47, 97, 0, 0},
+		{47, 97, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2},
+		{47, 97, 0, 0, 44, 115, 0, 0, 0xc3, 0xa9, 0, 0},
+		{47, 97, 0, 0, 44, 105, 115, 0, 0, 0, 0, 1, 97, 98, 0},
+		{47, 97, 0, 0, 44, 105, 122, 105, 0, 0, 0, 0, 0, 0, 0, 1, 1, 2, 3, 4, 0, 0, 0, 2},
+		{47, 97, 0, 0, 44, 91, 105, 122, 93, 105, 0, 0, 0, 0, 0, 1, 9, 9, 9, 9},
+	}
+
+	for _, opts := range []DecodeOptions{{}, lenient, {UnknownTagPolicy: UnknownTagSkip}} {
+		for _, input := range inputs {
+			address, args, readErr := ReadMessageWith(bytes.NewReader(input), opts)
+			m, parseErr := ParseMessageWith(input, opts)
+			noCopy, noCopyErr := ParseMessageNoCopyWith(input, opts)
+
+			if (readErr == nil) != (parseErr == nil) {
+				t.Errorf("%v with %+v: ReadMessageWith returned %v, but ParseMessageWith returned %v", input, opts, readErr, parseErr)
+			} else if readErr == nil {
+				expectSame(t, Message{address, args}, m)
+				expectSame(t, m, noCopy)
+			}
+			expectSame(t, parseErr, noCopyErr)
+		}
+	}
+}
+
+func TestParseMessageMaxBlobSize(t *T) {
+	data, err := AppendMessage(nil, OSCAddressPattern("/a"), OSCBlob(make([]byte, 16)))
+	expectNil(t, err)
+
+	_, err = ParseMessageWith(data, DecodeOptions{MaxBlobSize: 8})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %#v", err)
+	}
+
+	_, _, err = ReadMessageWith(bytes.NewReader(data), DecodeOptions{Strict: true, MaxBlobSize: 8})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge from ReadMessageWith, got %#v", err)
+	}
+
+	// A blob at the limit is accepted, and the default limit is much larger.
+	m, err := ParseMessageWith(data, DecodeOptions{MaxBlobSize: 16})
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCBlob(make([]byte, 16))}, m.Args)
+
+	_, _, err = ReadMessageWith(bytes.NewReader(data), DecodeOptions{})
+	expectNil(t, err)
+}
+
+func TestParseMessageErrors(t *T) {
+	valid, _ := AppendMessage(nil, OSCAddressPattern("/a"), OSCInt32(1), OSCString("abc"), OSCBlob([]byte{1}))
+
+	// Every truncation of a valid message is an error.
+	for n := 0; n < len(valid); n++ {
+		_, err := ParseMessage(valid[:n])
+		if err == nil {
+			t.Errorf("expected an error for a message truncated to %d bytes", n)
+		}
+	}
+
+	inputs := map[string][]byte{
+		"trailing data": append(append([]byte{}, valid...), 0, 0, 0, 0),
+		"no comma": []byte{47,97,0,0, 105,0,0,0, 0,0,0,1},
+		"bad padding": []byte{47,97,0,1, 44,0,0,0},
+		"unknown tag": []byte{47,97,0,0, 44,122,0,0, 0,0,0,1},
+		"unterminated array": []byte{47,97,0,0, 44,91,105,0, 0,0,0,1},
+		"negative blob": []byte{47,97,0,0, 44,98,0,0, 0xff,0xff,0xff,0xff},
+	}
+
+	for name, input := range inputs {
+		_, err := ParseMessage(input)
+		if _, ok := err.(OSCReadError); !ok {
+			t.Errorf("%s: expected an OSCReadError, got %#v", name, err)
+		}
+	}
+
+	_, err := ParseMessage([]byte{97,0,0,0, 44,0,0,0})
+	if _, ok := err.(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for an invalid address, got %#v", err)
+	}
+}
+
+var benchmarkPacket, _ = AppendMessage(nil, OSCAddressPattern("/sensor/12/imu"),
+	OSCFloat32(0.1), OSCFloat32(0.2), OSCFloat32(0.3),
+	OSCString("label"), OSCBlob([]byte{1,2,3,4,5,6,7,8}), OSCInt32(123456))
+
+func BenchmarkReadMessage(b *B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ReadMessage(bytes.NewReader(benchmarkPacket))
+	}
+}
+
+func BenchmarkParseMessage(b *B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ParseMessage(benchmarkPacket)
+	}
+}
+
+func BenchmarkParseMessageNoCopy(b *B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ParseMessageNoCopy(benchmarkPacket)
+	}
+}
+
+func BenchmarkParseMessageCopy(b *B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		m, _ := ParseMessage(benchmarkPacket)
+		m.Copy()
+	}
+}
@@ -0,0 +1,414 @@
+package gosc
+
+import (
+	"strings"
+)
+
+// A compiled OSC address pattern, which can be matched against any number of
+// addresses without re-parsing. Supports the OSC 1.0 wildcards:
+//
+//	?          matches any single character
+//	*          matches any sequence of zero or more characters
+//	[a-z]      matches any character in the set (ranges allowed)
+//	[!a-z]     matches any character not in the set
+//	{foo,bar}  matches any of the comma-separated strings
+//
+// None of these match across a forward slash. In addition, the OSC 1.1 path
+// traversal wildcard "//" matches any number (including zero) of complete
+// address parts, so "//mute" matches "/mute" as well as "/mixer/1/mute".
+type Pattern struct {
+	source string
+	parts  []patternPart
+}
+
+// A single part of a pattern (between slashes).
+type patternPart struct {
+	// Descend is set for the "//" path traversal wildcard, in which case there
+	// are no tokens.
+	descend bool
+	tokens  []patternToken
+}
+
+type patternTokenKind int
+
+const (
+	tokenLiteral patternTokenKind = iota
+	tokenAnyChar
+	tokenAnyString
+	tokenCharClass
+	tokenAlternatives
+)
+
+type patternToken struct {
+	kind patternTokenKind
+
+	// Literal text (tokenLiteral) or the set of characters (tokenCharClass).
+	text string
+
+	// Whether the character class is negated ([!...]).
+	negate bool
+
+	// The possible strings in an alternation ({foo,bar}).
+	alternatives []string
+}
+
+// Parses an OSC address pattern. Returns an OSCArgumentError (with the
+// position of the problem) if the pattern is malformed.
+func CompilePattern(pattern string) (*Pattern, error) {
+	p := &Pattern{source: pattern}
+	if err := parsePattern(pattern, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Like CompilePattern, but panics if the pattern is malformed. Intended for
+// patterns that are known in advance.
+func MustCompilePattern(pattern string) *Pattern {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// Checks that a pattern is well-formed, returning the same errors as
+// CompilePattern, but without building anything (so nothing is allocated
+// unless the pattern is malformed).
+func checkPattern(pattern string) error {
+	return parsePattern(pattern, nil)
+}
+
+// Parses a pattern, adding its parts to p, or only checking it if p is nil.
+func parsePattern(pattern string, p *Pattern) error {
+	if len(pattern) == 0 || pattern[0] != '/' {
+		return OSCArgumentErrorf("address pattern \"%s\" must start with a forward slash", pattern).withKind(ErrInvalidAddress)
+	}
+
+	descending := false
+
+	// Each iteration parses one part, starting just after a slash.
+	for start := 1; ; {
+		if start < len(pattern) && pattern[start] == '/' {
+			// "//": path traversal wildcard. Any number of slashes in a row
+			// are treated the same as two.
+			if !descending && p != nil {
+				p.parts = append(p.parts, patternPart{descend: true})
+			}
+			descending = true
+			start++
+			continue
+		}
+
+		var part *patternPart
+		if p != nil {
+			p.parts = append(p.parts, patternPart{})
+			part = &p.parts[len(p.parts)-1]
+		}
+
+		end, err := compilePatternPart(pattern, start, part)
+		if err != nil {
+			return invalidAddress(err)
+		}
+		descending = false
+
+		if end >= len(pattern) {
+			return nil
+		}
+
+		// pattern[end] is a slash.
+		start = end + 1
+	}
+}
+
+// Parses a single part of the pattern, starting at position start and ending
+// at the next slash (or the end of the pattern), into part (unless it's nil).
+// Returns the position of the slash that ended it.
+func compilePatternPart(pattern string, start int, part *patternPart) (int, error) {
+	literalStart := -1
+
+	// Adds a token to the part, after any literal text before it.
+	add := func(end int, tok patternToken) {
+		if part == nil {
+			return
+		}
+
+		if literalStart >= 0 {
+			part.tokens = append(part.tokens, patternToken{kind: tokenLiteral, text: pattern[literalStart:end]})
+		}
+
+		// Consecutive stars are equivalent to a single star.
+		n := len(part.tokens)
+		if tok.kind == tokenAnyString && n > 0 && part.tokens[n-1].kind == tokenAnyString {
+			return
+		}
+
+		part.tokens = append(part.tokens, tok)
+	}
+
+	i := start
+	for i < len(pattern) && pattern[i] != '/' {
+		switch c := pattern[i]; c {
+		case '?', '*':
+			kind := tokenAnyChar
+			if c == '*' {
+				kind = tokenAnyString
+			}
+			add(i, patternToken{kind: kind})
+			literalStart = -1
+			i++
+		case '[':
+			var tok patternToken
+			end, err := compileCharClass(pattern, i, &tok, part != nil)
+			if err != nil {
+				return i, err
+			}
+			add(i, tok)
+			literalStart = -1
+			i = end + 1
+		case '{':
+			var tok patternToken
+			end, err := compileAlternatives(pattern, i, &tok, part != nil)
+			if err != nil {
+				return i, err
+			}
+			add(i, tok)
+			literalStart = -1
+			i = end + 1
+		case ']', '}':
+			return i, OSCArgumentErrorf("unmatched '%c' at position %d in address pattern \"%s\"", c, i, pattern)
+		case ',':
+			return i, OSCArgumentErrorf("disallowed character ',' found at position %d outside of '{' in address pattern \"%s\"", i, pattern)
+		default:
+			if literalStart < 0 {
+				literalStart = i
+			}
+			i++
+		}
+	}
+
+	if part != nil && literalStart >= 0 {
+		part.tokens = append(part.tokens, patternToken{kind: tokenLiteral, text: pattern[literalStart:i]})
+	}
+
+	return i, nil
+}
+
+// Parses a character class ("[...]") starting at position start into tok
+// (filling in its set of characters only if build is set). Returns the
+// position of the closing bracket.
+func compileCharClass(pattern string, start int, tok *patternToken, build bool) (int, error) {
+	tok.kind = tokenCharClass
+	var set []byte
+	empty := true
+
+	i := start + 1
+	if i < len(pattern) && pattern[i] == '!' {
+		tok.negate = true
+		i++
+	}
+
+	first := i
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		c := pattern[i]
+
+		if c == '/' || c == '[' || c == '{' || c == '}' {
+			break
+		}
+
+		// A dash between two characters is a range; a dash at the start or
+		// end of the class is a literal dash.
+		if c == '-' && i > first && i+1 < len(pattern) && pattern[i+1] != ']' {
+			lo, hi := pattern[i-1], pattern[i+1]
+			if hi < lo {
+				return i, OSCArgumentErrorf("invalid range '%c-%c' at position %d in address pattern \"%s\"", lo, hi, i-1, pattern)
+			}
+			for r := int(lo) + 1; build && r <= int(hi); r++ {
+				set = append(set, byte(r))
+			}
+			i++
+			continue
+		}
+
+		if build {
+			set = append(set, c)
+		}
+		empty = false
+	}
+
+	if i >= len(pattern) || pattern[i] != ']' {
+		return start, OSCArgumentErrorf("unterminated '[' at position %d in address pattern \"%s\"", start, pattern)
+	}
+
+	if empty {
+		return start, OSCArgumentErrorf("empty character class at position %d in address pattern \"%s\"", start, pattern)
+	}
+
+	tok.text = string(set)
+	return i, nil
+}
+
+// Parses a list of alternatives ("{foo,bar}") starting at position start into
+// tok (splitting out the alternatives only if build is set). Returns the
+// position of the closing brace.
+func compileAlternatives(pattern string, start int, tok *patternToken, build bool) (int, error) {
+	tok.kind = tokenAlternatives
+
+	i := start + 1
+	for ; i < len(pattern) && pattern[i] != '}'; i++ {
+		switch c := pattern[i]; c {
+		case '/':
+			return start, OSCArgumentErrorf("unterminated '{' at position %d in address pattern \"%s\"", start, pattern)
+		case '{', '[', ']', '*', '?':
+			return i, OSCArgumentErrorf("unexpected '%c' at position %d inside '{' in address pattern \"%s\"", c, i, pattern)
+		}
+	}
+
+	if i >= len(pattern) {
+		return start, OSCArgumentErrorf("unterminated '{' at position %d in address pattern \"%s\"", start, pattern)
+	}
+
+	if build {
+		tok.alternatives = strings.Split(pattern[start+1:i], ",")
+	}
+	return i, nil
+}
+
+// Returns the original (uncompiled) pattern.
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Match returns true if the address is matched by the pattern.
+func (p *Pattern) Match(address string) bool {
+	if len(address) == 0 || address[0] != '/' {
+		return false
+	}
+
+	return matchParts(p.parts, strings.Split(address[1:], "/"))
+}
+
+// Match returns true if the address is matched by this address pattern. The
+// pattern is compiled on every call; use CompilePattern to match many
+// addresses against the same pattern. Malformed patterns match nothing.
+func (s OSCAddressPattern) Match(address string) bool {
+	p, err := CompilePattern(string(s))
+	if err != nil {
+		return false
+	}
+
+	return p.Match(address)
+}
+
+// Matches the pattern parts against the address segments by tracking every
+// segment index the parts so far can reach, rather than by backtracking, so
+// that the time taken is bounded by len(parts) * len(segments) part matches
+// however many "//" wildcards there are.
+func matchParts(parts []patternPart, segments []string) bool {
+	// reach[i] is whether the parts so far can match segments[:i].
+	reach := make([]bool, len(segments) + 1)
+	next := make([]bool, len(segments) + 1)
+	reach[0] = true
+
+	for _, part := range parts {
+		for i := range next {
+			next[i] = false
+		}
+
+		any := false
+		for i, ok := range reach {
+			if !ok {
+				continue
+			}
+
+			if part.descend {
+				// Skips any number of segments. Once a later index is set,
+				// so is everything after it.
+				for j := i; j < len(next) && !next[j]; j++ {
+					next[j] = true
+				}
+				any = true
+			} else if i < len(segments) && part.match(segments[i]) {
+				next[i+1] = true
+				any = true
+			}
+		}
+
+		if !any {
+			return false
+		}
+
+		reach, next = next, reach
+	}
+
+	return reach[len(segments)]
+}
+
+// Returns true if the part matches a single address segment.
+func (part patternPart) match(segment string) bool {
+	return matchTokens(part.tokens, segment)
+}
+
+// Matches the tokens against a string in the same way as matchParts, by
+// tracking the offsets in the string the tokens so far can reach. A star only
+// ever extends the reachable offsets to the end of the string, so the time
+// taken is bounded by len(tokens) * len(s) (times the number of alternatives).
+func matchTokens(tokens []patternToken, s string) bool {
+	// reach[i] is whether the tokens so far can match s[:i].
+	reach := make([]bool, len(s) + 1)
+	next := make([]bool, len(s) + 1)
+	reach[0] = true
+
+	for _, tok := range tokens {
+		for i := range next {
+			next[i] = false
+		}
+
+		any := false
+		for i, ok := range reach {
+			if !ok {
+				continue
+			}
+
+			switch tok.kind {
+			case tokenLiteral:
+				if strings.HasPrefix(s[i:], tok.text) {
+					next[i+len(tok.text)] = true
+					any = true
+				}
+			case tokenAnyChar:
+				if i < len(s) {
+					next[i+1] = true
+					any = true
+				}
+			case tokenAnyString:
+				for j := i; j <= len(s) && !next[j]; j++ {
+					next[j] = true
+				}
+				any = true
+			case tokenCharClass:
+				if i < len(s) && (strings.IndexByte(tok.text, s[i]) >= 0) != tok.negate {
+					next[i+1] = true
+					any = true
+				}
+			case tokenAlternatives:
+				for _, alt := range tok.alternatives {
+					if strings.HasPrefix(s[i:], alt) {
+						next[i+len(alt)] = true
+						any = true
+					}
+				}
+			}
+		}
+
+		if !any {
+			return false
+		}
+
+		reach, next = next, reach
+	}
+
+	return reach[len(s)]
+}
@@ -0,0 +1,113 @@
+package gosc
+
+import (
+	"strings"
+	. "testing"
+	"time"
+)
+
+func expectMatch(t *T, pattern, address string, expected bool) {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		t.Errorf("failed to compile pattern %q: %s", pattern, err)
+		return
+	}
+
+	if p.Match(address) != expected {
+		t.Errorf("expected Match(%q, %q) to be %v", pattern, address, expected)
+	}
+}
+
+func TestPatternLiteral(t *T) {
+	expectMatch(t, "/foo/bar", "/foo/bar", true)
+	expectMatch(t, "/foo/bar", "/foo/baz", false)
+	expectMatch(t, "/foo/bar", "/foo", false)
+	expectMatch(t, "/foo", "/foo/bar", false)
+	expectMatch(t, "/foo", "foo", false)
+	expectMatch(t, "/", "/", true)
+}
+
+func TestPatternWildcards(t *T) {
+	expectMatch(t, "/fo?", "/foo", true)
+	expectMatch(t, "/fo?", "/fo", false)
+	expectMatch(t, "/?/x", "//x", false)
+
+	expectMatch(t, "/mixer/*/mute", "/mixer/1/mute", true)
+	expectMatch(t, "/mixer/*/mute", "/mixer/channel12/mute", true)
+	expectMatch(t, "/mixer/*/mute", "/mixer/1/2/mute", false)
+	expectMatch(t, "/*", "/", true)
+	expectMatch(t, "/f*r", "/foobar", true)
+	expectMatch(t, "/f*r", "/foobaz", false)
+	expectMatch(t, "/*a*b*", "/xxaxxbxx", true)
+	expectMatch(t, "/*a*b*", "/xxbxxaxx", false)
+}
+
+func TestPatternCharClass(t *T) {
+	expectMatch(t, "/ch[0-9]", "/ch7", true)
+	expectMatch(t, "/ch[0-9]", "/chx", false)
+	expectMatch(t, "/ch[0-9]", "/ch10", false)
+	expectMatch(t, "/ch[abc]", "/chb", true)
+	expectMatch(t, "/ch[!abc]", "/chb", false)
+	expectMatch(t, "/ch[!abc]", "/chd", true)
+	expectMatch(t, "/ch[a-]", "/ch-", true)
+	expectMatch(t, "/ch[-a]", "/ch-", true)
+	expectMatch(t, "/x[a-cx-z]", "/xy", true)
+	expectMatch(t, "/x[a-cx-z]", "/xm", false)
+}
+
+func TestPatternAlternatives(t *T) {
+	expectMatch(t, "/{foo,bar}/go", "/foo/go", true)
+	expectMatch(t, "/{foo,bar}/go", "/bar/go", true)
+	expectMatch(t, "/{foo,bar}/go", "/baz/go", false)
+	expectMatch(t, "/a{b,bc}d", "/abcd", true)
+	expectMatch(t, "/a{,x}b", "/ab", true)
+}
+
+func TestPatternPathTraversal(t *T) {
+	expectMatch(t, "//mute", "/mute", true)
+	expectMatch(t, "//mute", "/mixer/1/mute", true)
+	expectMatch(t, "//mute", "/mixer/1/solo", false)
+	expectMatch(t, "/mixer//mute", "/mixer/mute", true)
+	expectMatch(t, "/mixer//mute", "/mixer/a/b/c/mute", true)
+	expectMatch(t, "/mixer//mute", "/other/mute", false)
+	expectMatch(t, "//[0-9]/*", "/mixer/3/fader", true)
+}
+
+func TestPatternErrors(t *T) {
+	for _, pattern := range []string{"", "foo", "/[abc", "/abc]", "/{a,b", "/a}", "/[]", "/[!]", "/[z-a]", "/{a/b}", "/[a/b]", "/a,b", "/a//{b"} {
+		_, err := CompilePattern(pattern)
+		if _, ok := err.(OSCArgumentError); !ok {
+			t.Errorf("expected an OSCArgumentError for pattern %q, got %#v", pattern, err)
+		}
+
+		// Checking a pattern finds the same problems as compiling it.
+		expectSame(t, err, checkPattern(pattern))
+	}
+
+	for _, pattern := range []string{"/", "//", "/a//b", "/[-]", "/[a-]", "/[!a-c]x", "/{}", "/{a,,b}"} {
+		expectNil(t, checkPattern(pattern))
+	}
+}
+
+func TestOSCAddressPatternMatch(t *T) {
+	expectSame(t, true, OSCAddressPattern("/mixer/*/mute").Match("/mixer/3/mute"))
+	expectSame(t, false, OSCAddressPattern("/mixer/*/mute").Match("/mixer/3/solo"))
+	expectSame(t, false, OSCAddressPattern("/mixer/[").Match("/mixer/["))
+}
+
+// Patterns come from the network, so matching must not blow up with the
+// number of wildcards (as naive backtracking does).
+func TestPatternManyWildcards(t *T) {
+	address := "/" + strings.Repeat("a", 40)
+	deep := strings.Repeat("/a", 30)
+
+	start := time.Now()
+	expectMatch(t, "/*a*a*a*a*a*a*a*a*b", address, false)
+	expectMatch(t, "/*a*a*a*a*a*a*a*a*a", address, true)
+	expectMatch(t, "//a//a//a//a//a//a//a//a//b", deep, false)
+	expectMatch(t, "//*//*//*//*//*//*//*//*//a", deep, true)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("matching took %s", elapsed)
+	}
+}
@@ -0,0 +1,110 @@
+package gosc
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Decodes an argument of a custom type from an input stream. It must read
+// exactly the argument's data (including any padding), which must be a
+// multiple of four bytes long.
+type TypeDecoder func(in io.Reader) (OSCArg, error)
+
+// A TypeRegistry maps type tags to decoders for argument types that aren't
+// built into the package, such as vendor-specific types. Messages are
+// decoded using DefaultTypeRegistry unless DecodeOptions specify another, so
+// that different sockets can accept different dialects.
+//
+// A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	mu       sync.RWMutex
+	decoders map[OSCTypeTag]TypeDecoder
+}
+
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{decoders: make(map[OSCTypeTag]TypeDecoder)}
+}
+
+// The registry used by ReadMessage, ParseMessage, and by DecodeOptions that
+// don't specify one.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// RegisterType registers a decoder for a custom type tag in the
+// DefaultTypeRegistry.
+func RegisterType(tag OSCTypeTag, decoder TypeDecoder) error {
+	return DefaultTypeRegistry.Register(tag, decoder)
+}
+
+// Register adds a decoder for a custom type tag. Returns an OSCArgumentError
+// if the tag is already registered, is one of the built-in tags, or is a
+// character with a special meaning in tag strings or signatures.
+func (r *TypeRegistry) Register(tag OSCTypeTag, decoder TypeDecoder) error {
+	if decoder == nil {
+		return OSCArgumentErrorf("nil decoder for type tag '%c'", tag)
+	}
+
+	if isBuiltinTag(tag) {
+		return OSCArgumentErrorf("type tag '%c' is built in", tag)
+	}
+
+	if tag <= ' ' || tag > '~' || bytes.IndexByte([]byte(",[]().?*+"), byte(tag)) >= 0 {
+		return OSCArgumentErrorf("type tag '%c' is reserved", tag)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.decoders[tag]; ok {
+		return OSCArgumentErrorf("type tag '%c' is already registered", tag)
+	}
+
+	r.decoders[tag] = decoder
+	return nil
+}
+
+// Lookup returns the decoder registered for a type tag, if any.
+func (r *TypeRegistry) Lookup(tag OSCTypeTag) (TypeDecoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decoder, ok := r.decoders[tag]
+	return decoder, ok
+}
+
+func isBuiltinTag(tag OSCTypeTag) bool {
+	switch tag {
+	case OSC_TYPE_INT32, OSC_TYPE_FLOAT32, OSC_TYPE_STRING, OSC_TYPE_BLOB,
+		OSC_ETYPE_INT64, OSC_ETYPE_TIMETAG, OSC_ETYPE_FLOAT64, OSC_ETYPE_STRING_ALT,
+		OSC_ETYPE_CHAR, OSC_ETYPE_RGBA, OSC_ETYPE_MIDI, OSC_ETYPE_TRUE, OSC_ETYPE_FALSE,
+		OSC_ETYPE_NIL, OSC_ETYPE_INFINITY, OSC_ETYPE_ARRAY_START, OSC_ETYPE_ARRAY_END:
+		return true
+	}
+
+	return false
+}
+
+// Decodes a custom argument from the input, checking that the decoder
+// behaved. The count function reports how many bytes have been read from in.
+func decodeCustom(in io.Reader, count func() int, tag OSCTypeTag, decoder TypeDecoder) (OSCArg, error) {
+	start := count()
+
+	arg, err := decoder(in)
+	if err != nil {
+		return nil, OSCReadErrorf("failed to read custom type '%c': %w", tag, err)
+	}
+
+	if arg == nil {
+		return nil, OSCReadErrorf("decoder for custom type '%c' returned nil", tag)
+	}
+
+	if arg.Tag() != tag {
+		return nil, OSCReadErrorf("decoder for custom type '%c' returned an argument with tag '%c'", tag, arg.Tag())
+	}
+
+	if n := count() - start; n % OSC_BYTE_ALIGNMENT != 0 {
+		return nil, OSCReadErrorf("decoder for custom type '%c' read %d bytes, which is not a multiple of %d", tag, n, OSC_BYTE_ALIGNMENT)
+	}
+
+	return arg, nil
+}
@@ -0,0 +1,145 @@
+package gosc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	. "testing"
+)
+
+// A vendor-specific UUID argument.
+type testUUID [16]byte
+
+func readTestUUID(in io.Reader) (OSCArg, error) {
+	var u testUUID
+	_, err := io.ReadFull(in, u[:])
+	return u, err
+}
+
+func (u testUUID) Tag() OSCTypeTag {
+	return OSCTypeTag('u')
+}
+
+func (u testUUID) Valid() error {
+	return nil
+}
+
+func (u testUUID) WriteTo(out io.Writer) (int, error) {
+	return out.Write(u[:])
+}
+
+func TestRegisterType(t *T) {
+	expectNil(t, RegisterType(OSCTypeTag('u'), readTestUUID))
+
+	// The registration is global, so it mustn't leak into other tests.
+	t.Cleanup(func() {
+		DefaultTypeRegistry.mu.Lock()
+		defer DefaultTypeRegistry.mu.Unlock()
+		delete(DefaultTypeRegistry.decoders, OSCTypeTag('u'))
+	})
+
+	u := testUUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	data, err := AppendMessage(nil, OSCAddressPattern("/device"), OSCInt32(1), u, OSCString("x"))
+	expectNil(t, err)
+
+	address, args, err := ReadMessage(bytes.NewReader(data))
+	expectNil(t, err)
+	expectSame(t, Message{OSCAddressPattern("/device"), []OSCArg{OSCInt32(1), u, OSCString("x")}}, Message{address, args})
+
+	m, err := ParseMessage(data)
+	expectNil(t, err)
+	expectSame(t, []OSCArg{OSCInt32(1), u, OSCString("x")}, m.Args)
+}
+
+func TestTypeRegistryPerDecoder(t *T) {
+	quaternion := NewTypeRegistry()
+	expectNil(t, quaternion.Register(OSCTypeTag('q'), func(in io.Reader) (OSCArg, error) {
+		var q testQuaternion
+		for i := range q {
+			f, err := ReadOSCFloat32(in)
+			if err != nil {
+				return nil, err
+			}
+			q[i] = float32(f)
+		}
+		return q, nil
+	}))
+
+	q := testQuaternion{1, 0, 0, 0.5}
+	data, err := AppendMessage(nil, OSCAddressPattern("/pose"), q)
+	expectNil(t, err)
+
+	address, args, err := ReadMessageWith(bytes.NewReader(data), DecodeOptions{Types: quaternion})
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/pose"), address)
+	expectSame(t, []OSCArg{q}, args)
+
+	// Other decoders don't know about it.
+	_, _, err = ReadMessage(bytes.NewReader(data))
+	if !errors.Is(err, ErrUnknownTag) {
+		t.Errorf("expected ErrUnknownTag from the default registry, got %#v", err)
+	}
+
+	// Strict doesn't affect custom types.
+	_, _, err = ReadMessageWith(bytes.NewReader(data), DecodeOptions{Strict: true, Types: quaternion})
+	expectNil(t, err)
+}
+
+type testQuaternion [4]float32
+
+func (q testQuaternion) Tag() OSCTypeTag {
+	return OSCTypeTag('q')
+}
+
+func (q testQuaternion) Valid() error {
+	return nil
+}
+
+func (q testQuaternion) WriteTo(out io.Writer) (int, error) {
+	total := 0
+	for _, f := range q {
+		n, err := OSCFloat32(f).WriteTo(out)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func TestTypeRegistryErrors(t *T) {
+	r := NewTypeRegistry()
+
+	expectArgumentError(t, r.Register(OSC_TYPE_INT32, readTestUUID), "type tag 'i' is built in")
+	expectArgumentError(t, r.Register(OSCTypeTag(','), readTestUUID), "type tag ',' is reserved")
+	expectArgumentError(t, r.Register(OSCTypeTag('*'), readTestUUID), "reserved")
+	expectArgumentError(t, r.Register(OSCTypeTag(0), readTestUUID), "reserved")
+	expectArgumentError(t, r.Register(OSCTypeTag('u'), nil), "nil decoder")
+
+	expectNil(t, r.Register(OSCTypeTag('u'), readTestUUID))
+	expectArgumentError(t, r.Register(OSCTypeTag('u'), readTestUUID), "already registered")
+}
+
+func TestTypeRegistryBadDecoders(t *T) {
+	r := NewTypeRegistry()
+	r.Register(OSCTypeTag('x'), func(in io.Reader) (OSCArg, error) {
+		var b [2]byte
+		io.ReadFull(in, b[:])
+		return testUUID{}, nil
+	})
+	r.Register(OSCTypeTag('y'), func(in io.Reader) (OSCArg, error) {
+		return nil, errors.New("bad data")
+	})
+
+	input := []byte{47, 97, 0, 0, 44, 120, 0, 0, 0, 0, 0, 0}
+	_, _, err := ReadMessageWith(bytes.NewReader(input), DecodeOptions{Types: r})
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError for a decoder returning the wrong type, got %#v", err)
+	}
+
+	input[5] = 'y'
+	_, _, err = ReadMessageWith(bytes.NewReader(input), DecodeOptions{Types: r})
+	if e, ok := err.(OSCReadError); !ok || e.Tag != OSCTypeTag('y') {
+		t.Errorf("expected an OSCReadError for a failing decoder, got %#v", err)
+	}
+}
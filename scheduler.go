@@ -0,0 +1,290 @@
+package gosc
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// A Clock tells the time, and waits for time to pass. The Scheduler uses one
+// so that tests (or simulations) can control time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the real, wall-clock time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// What a Scheduler does with a message whose time has already passed, either
+// when it is scheduled or when Run gets to it.
+type LatePolicy int
+
+const (
+	// Deliver it immediately.
+	LateDeliver LatePolicy = iota
+
+	// Discard it.
+	LateDrop
+
+	// Don't deliver it, and return an error (of kind ErrLate) from Schedule,
+	// or pass one to the ErrorHandler if the message became late while it was
+	// queued.
+	LateReport
+)
+
+// The kind of error reported for late messages under LateReport.
+var ErrLate = errors.New("scheduled time has already passed")
+
+// A Scheduler holds messages until their timetags, and then delivers them
+// through a callback, as OSC requires of time-tagged bundles. Messages due at
+// the same time are delivered in the order they were scheduled, and messages
+// with the timetag OSC_TIMETAG_IMMEDIATELY are delivered right away.
+// Deliveries all happen on the goroutine calling Run, e.g.:
+//
+//	s := &gosc.Scheduler{Deliver: func(m gosc.Message, at gosc.OSCTimetag) {
+//		dispatcher.Dispatch(m.Address, m.Args)
+//	}}
+//	go s.Run(ctx)
+//	...
+//	s.SchedulePacket(packet)
+//
+// A Scheduler is safe for concurrent use.
+type Scheduler struct {
+	// Deliver is called with each message when it is due.
+	Deliver func(msg Message, at OSCTimetag)
+
+	// Late decides what happens to messages that are late: those scheduled
+	// after their time, and those still queued after their time because Run
+	// wasn't running or was held up by a slow Deliver.
+	Late LatePolicy
+
+	// Tolerance is how far in the past a timetag can be before the message
+	// counts as late; messages within the tolerance are simply delivered
+	// right away. Under LateDrop and LateReport, a small tolerance allows for
+	// the time taken to deliver messages due just before.
+	Tolerance time.Duration
+
+	// ErrorHandler, if set, is called (on the goroutine calling Run) for each
+	// message that becomes late while queued under LateReport.
+	ErrorHandler func(err error)
+
+	// Clock is the source of time. Defaults to SystemClock.
+	Clock Clock
+
+	mu    sync.Mutex
+	queue scheduleQueue
+	seq   uint64
+	wake  chan struct{}
+}
+
+type scheduledMessage struct {
+	at  OSCTimetag
+	seq uint64
+	msg Message
+}
+
+// A min-heap of messages, ordered by timetag and then by the order they were
+// scheduled in.
+type scheduleQueue []scheduledMessage
+
+func (q scheduleQueue) Len() int {
+	return len(q)
+}
+
+func (q scheduleQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at.Before(q[j].at)
+	}
+
+	return q[i].seq < q[j].seq
+}
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	*q = append(*q, x.(scheduledMessage))
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func (s *Scheduler) clock() Clock {
+	if s.Clock == nil {
+		return SystemClock
+	}
+
+	return s.Clock
+}
+
+// Returns the channel used to wake Run. Must be called with s.mu held.
+func (s *Scheduler) wakeChan() chan struct{} {
+	if s.wake == nil {
+		s.wake = make(chan struct{}, 1)
+	}
+
+	return s.wake
+}
+
+// Schedule queues a message for delivery at the specified time. Messages that
+// are already late are handled according to the Late policy.
+func (s *Scheduler) Schedule(at OSCTimetag, msg Message) error {
+	if !at.IsImmediate() {
+		if late := s.clock().Now().Sub(at.Time()); late > s.Tolerance {
+			switch s.Late {
+			case LateDrop:
+				return nil
+			case LateReport:
+				return OSCArgumentErrorf("message to %s is %s late", msg.Address, late).withKind(ErrLate)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	heap.Push(&s.queue, scheduledMessage{at, s.seq, msg})
+
+	select {
+	case s.wakeChan() <- struct{}{}:
+	default:
+		// Run has already been woken.
+	}
+
+	return nil
+}
+
+// SchedulePacket schedules a message for immediate delivery, or every message
+// in a bundle (including nested bundles) at the bundle's timetag. All of the
+// messages are scheduled even if some are late; the first error is returned.
+func (s *Scheduler) SchedulePacket(packet OSCPacket) error {
+	switch p := packet.(type) {
+	case Message:
+		return s.Schedule(OSC_TIMETAG_IMMEDIATELY, p)
+	case OSCBundle:
+		var first error
+		for _, elem := range p.Elements {
+			var err error
+			if m, ok := elem.(Message); ok {
+				err = s.Schedule(p.Timetag, m)
+			} else {
+				err = s.SchedulePacket(elem)
+			}
+
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+
+	return OSCArgumentErrorf("can't schedule a packet of type %T", packet)
+}
+
+// Pending returns the number of messages waiting to be delivered.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queue.Len()
+}
+
+// Run delivers messages as they become due, until the context is done; it
+// then returns the context's error. Messages still pending stay queued, and
+// will be delivered if Run is called again. Run returns an error straight away
+// if the Scheduler has no Deliver function.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if s.Deliver == nil {
+		return OSCArgumentErrorf("Scheduler has no Deliver function")
+	}
+
+	clock := s.clock()
+
+	// When woken by the timer, lateness is measured from the time Run meant
+	// to wake up, so that the timer's own delay doesn't count.
+	var woken time.Time
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		wake := s.wakeChan()
+
+		now := clock.Now()
+		wait := time.Duration(-1)
+
+		since := now
+		if !woken.IsZero() {
+			since = woken
+			woken = time.Time{}
+		}
+
+		var due, late []scheduledMessage
+		for s.queue.Len() > 0 {
+			next := s.queue[0]
+			if !next.at.IsImmediate() && next.at.Time().After(now) {
+				wait = next.at.Time().Sub(now)
+				break
+			}
+
+			heap.Pop(&s.queue)
+			if s.Late != LateDeliver && !next.at.IsImmediate() && since.Sub(next.at.Time()) > s.Tolerance {
+				late = append(late, next)
+			} else {
+				due = append(due, next)
+			}
+		}
+
+		policy := s.Late
+		s.mu.Unlock()
+
+		for _, item := range late {
+			if policy == LateReport && s.ErrorHandler != nil {
+				s.ErrorHandler(OSCArgumentErrorf("message to %s is %s late", item.msg.Address, since.Sub(item.at.Time())).withKind(ErrLate))
+			}
+		}
+
+		for _, item := range due {
+			s.Deliver(item.msg, item.at)
+		}
+
+		if len(due) > 0 || len(late) > 0 {
+			// Time has passed while delivering; check again.
+			continue
+		}
+
+		var timer <-chan time.Time
+		if wait >= 0 {
+			timer = clock.After(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		case <-timer:
+			woken = now.Add(wait)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package gosc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	. "testing"
+	"time"
+)
+
+// A Clock that only moves when told to.
+type testClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []testTimer
+	created int
+}
+
+type testTimer struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := testTimer{c.now.Add(d), make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.created++
+	return t.c
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var waiting []testTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			waiting = append(waiting, t)
+		} else {
+			t.c <- c.now
+		}
+	}
+	c.timers = waiting
+}
+
+func (c *testClock) timersCreated() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.created
+}
+
+// Waits for the scheduler to start waiting on a new timer.
+func (c *testClock) waitForTimer(t *T, after int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); c.timersCreated() <= after; {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the scheduler to set a timer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type delivery struct {
+	address OSCAddressPattern
+	at      OSCTimetag
+}
+
+func startScheduler(t *T, s *Scheduler) chan delivery {
+	delivered := make(chan delivery, 16)
+	s.Deliver = func(m Message, at OSCTimetag) {
+		delivered <- delivery{m.Address, at}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("expected Run to return context.Canceled, got %v", err)
+		}
+	})
+
+	return delivered
+}
+
+func expectDelivery(t *T, delivered chan delivery, address OSCAddressPattern) {
+	t.Helper()
+
+	select {
+	case d := <-delivered:
+		expectSame(t, address, d.address)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for delivery of %s", address)
+	}
+}
+
+func expectNoDelivery(t *T, delivered chan delivery) {
+	t.Helper()
+
+	select {
+	case d := <-delivered:
+		t.Fatalf("unexpected delivery of %s", d.address)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestScheduler(t *T) {
+	clock := newTestClock()
+	s := &Scheduler{Clock: clock}
+	delivered := startScheduler(t, s)
+
+	now := OSCTimetagFromTime(clock.Now())
+	timers := clock.timersCreated()
+
+	expectNil(t, s.Schedule(now.Add(2 * time.Second), Message{Address: "/b"}))
+	expectNil(t, s.Schedule(now.Add(time.Second), Message{Address: "/a"}))
+	expectNil(t, s.Schedule(now.Add(2 * time.Second), Message{Address: "/c"}))
+	expectNil(t, s.Schedule(OSC_TIMETAG_IMMEDIATELY, Message{Address: "/now"}))
+
+	expectDelivery(t, delivered, "/now")
+	clock.waitForTimer(t, timers)
+	expectNoDelivery(t, delivered)
+	expectSame(t, 3, s.Pending())
+
+	timers = clock.timersCreated()
+	clock.Advance(time.Second)
+	expectDelivery(t, delivered, "/a")
+
+	clock.waitForTimer(t, timers)
+	clock.Advance(time.Second)
+
+	// Same time: delivered in the order they were scheduled.
+	expectDelivery(t, delivered, "/b")
+	expectDelivery(t, delivered, "/c")
+	expectSame(t, 0, s.Pending())
+}
+
+func TestSchedulerWithoutDeliver(t *T) {
+	s := &Scheduler{}
+	expectNil(t, s.Schedule(OSC_TIMETAG_IMMEDIATELY, Message{Address: "/a"}))
+
+	err := s.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no Deliver function") {
+		t.Errorf("expected an error for a missing Deliver function, got %#v", err)
+	}
+	expectSame(t, 1, s.Pending())
+}
+
+func TestSchedulerLatePolicies(t *T) {
+	clock := newTestClock()
+	past := OSCTimetagFromTime(clock.Now().Add(-time.Second))
+	recent := OSCTimetagFromTime(clock.Now().Add(-time.Millisecond))
+
+	s := &Scheduler{Clock: clock, Tolerance: 10 * time.Millisecond}
+	delivered := startScheduler(t, s)
+
+	expectNil(t, s.Schedule(past, Message{Address: "/late"}))
+	expectDelivery(t, delivered, "/late")
+
+	s = &Scheduler{Clock: clock, Tolerance: 10 * time.Millisecond, Late: LateDrop}
+	delivered = startScheduler(t, s)
+
+	expectNil(t, s.Schedule(past, Message{Address: "/dropped"}))
+	expectNil(t, s.Schedule(recent, Message{Address: "/tolerated"}))
+	expectDelivery(t, delivered, "/tolerated")
+	expectNoDelivery(t, delivered)
+
+	s = &Scheduler{Clock: clock, Tolerance: 10 * time.Millisecond, Late: LateReport}
+	delivered = startScheduler(t, s)
+
+	err := s.Schedule(past, Message{Address: "/reported"})
+	if !errors.Is(err, ErrLate) {
+		t.Errorf("expected ErrLate, got %#v", err)
+	}
+	expectNoDelivery(t, delivered)
+}
+
+// Messages that become late while queued are subject to the Late policy too.
+func TestSchedulerLateInQueue(t *T) {
+	clock := newTestClock()
+	now := OSCTimetagFromTime(clock.Now())
+
+	var reported []error
+	s := &Scheduler{Clock: clock, Tolerance: 10 * time.Millisecond, Late: LateReport, ErrorHandler: func(err error) {
+		reported = append(reported, err)
+	}}
+
+	expectNil(t, s.Schedule(now.Add(time.Second), Message{Address: "/stale"}))
+	expectNil(t, s.Schedule(now.Add(2 * time.Second), Message{Address: "/tolerated"}))
+	expectNil(t, s.Schedule(OSC_TIMETAG_IMMEDIATELY, Message{Address: "/now"}))
+
+	// Run isn't running yet, so the first message is left waiting.
+	clock.Advance(2 * time.Second + 5 * time.Millisecond)
+
+	delivered := startScheduler(t, s)
+	expectDelivery(t, delivered, "/now")
+	expectDelivery(t, delivered, "/tolerated")
+	expectNoDelivery(t, delivered)
+
+	// Deliveries and reports happen on the same goroutine, so the report has
+	// been made by now.
+	expectSame(t, 1, len(reported))
+	if !errors.Is(reported[0], ErrLate) || !strings.Contains(reported[0].Error(), "/stale") {
+		t.Errorf("expected ErrLate for /stale, got %#v", reported[0])
+	}
+}
+
+func TestSchedulerSlowDeliver(t *T) {
+	clock := newTestClock()
+	now := OSCTimetagFromTime(clock.Now())
+
+	delivered := make(chan delivery, 16)
+	s := &Scheduler{Clock: clock, Late: LateDrop, Deliver: func(m Message, at OSCTimetag) {
+		if m.Address == "/slow" {
+			clock.Advance(2 * time.Second)
+		}
+		delivered <- delivery{m.Address, at}
+	}}
+
+	expectNil(t, s.Schedule(now.Add(time.Second), Message{Address: "/slow"}))
+	expectNil(t, s.Schedule(now.Add(2 * time.Second), Message{Address: "/held-up"}))
+	expectNil(t, s.Schedule(now.Add(4 * time.Second), Message{Address: "/later"}))
+
+	timers := clock.timersCreated()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The timer firing a little late doesn't make a message late, even with
+	// no tolerance.
+	clock.waitForTimer(t, timers)
+	clock.Advance(time.Second + time.Millisecond)
+	expectDelivery(t, delivered, "/slow")
+
+	// Delivering /slow took so long that /held-up is now late.
+	expectNoDelivery(t, delivered)
+	expectSame(t, 1, s.Pending())
+}
+
+func TestSchedulePacket(t *T) {
+	clock := newTestClock()
+	s := &Scheduler{Clock: clock, Late: LateReport}
+	delivered := startScheduler(t, s)
+
+	now := OSCTimetagFromTime(clock.Now())
+	timers := clock.timersCreated()
+
+	bundle := OSCBundle{now.Add(time.Second), []OSCPacket{
+		Message{Address: "/a"},
+		OSCBundle{now.Add(2 * time.Second), []OSCPacket{Message{Address: "/b"}}},
+	}}
+
+	expectNil(t, s.SchedulePacket(bundle))
+	expectNil(t, s.SchedulePacket(Message{Address: "/now"}))
+	expectDelivery(t, delivered, "/now")
+
+	clock.waitForTimer(t, timers)
+	timers = clock.timersCreated()
+	clock.Advance(time.Second)
+	expectDelivery(t, delivered, "/a")
+
+	clock.waitForTimer(t, timers)
+	clock.Advance(time.Second)
+	expectDelivery(t, delivered, "/b")
+
+	err := s.SchedulePacket(OSCBundle{now, []OSCPacket{Message{Address: "/late"}}})
+	if !errors.Is(err, ErrLate) {
+		t.Errorf("expected ErrLate for a late bundle, got %#v", err)
+	}
+}
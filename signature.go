@@ -0,0 +1,326 @@
+package gosc
+
+import (
+	"sync"
+)
+
+// A compiled argument signature, describing the type tags a message is
+// expected to have. A signature is written like a type tag string (the
+// leading comma is optional), with a few extensions:
+//
+//	.        matches any single argument
+//	x?       matches zero or one x
+//	x*       matches zero or more x
+//	x+       matches one or more x
+//	(...)    groups a sequence, e.g. ",s(if)*" for a name and any number of
+//	         int/float pairs
+//	[...]    matches an array whose elements match the inner signature
+//
+// where x is a type tag, '.', a group or an array. For example, ",iff" matches
+// exactly an int32 and two float32s, and ",s*" matches any number of strings.
+// Other type tags (including those of custom argument types) match arguments
+// with that tag.
+type Signature struct {
+	source string
+	elems  []signatureElem
+}
+
+type signatureElemKind int
+
+const (
+	sigTag signatureElemKind = iota
+	sigAny
+	sigGroup
+	sigArray
+)
+
+type signatureElem struct {
+	kind signatureElemKind
+	tag  OSCTypeTag
+
+	// The contents of a group or array.
+	elems []signatureElem
+
+	// The number of repetitions allowed; max is -1 for unlimited.
+	min, max int
+}
+
+// Parses an argument signature. Returns an OSCArgumentError (with the position
+// of the problem) if the signature is malformed.
+func CompileSignature(signature string) (*Signature, error) {
+	start := 0
+	if len(signature) > 0 && signature[0] == ',' {
+		start = 1
+	}
+
+	elems, end, err := compileSignatureElems(signature, start, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if end < len(signature) {
+		return nil, OSCArgumentErrorf("unmatched '%c' at position %d in signature \"%s\"", signature[end], end, signature)
+	}
+
+	return &Signature{source: signature, elems: elems}, nil
+}
+
+// Like CompileSignature, but panics if the signature is malformed. Intended
+// for signatures that are known in advance.
+func MustCompileSignature(signature string) *Signature {
+	s, err := CompileSignature(signature)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Parses a sequence of elements, up to the end of the signature or a closing
+// bracket. Returns the elements and the position where parsing stopped.
+func compileSignatureElems(signature string, start, depth int) ([]signatureElem, int, error) {
+	var elems []signatureElem
+
+	i := start
+	for i < len(signature) {
+		c := signature[i]
+
+		var elem signatureElem
+		switch c {
+		case ')', ']':
+			if depth == 0 {
+				return nil, i, OSCArgumentErrorf("unmatched '%c' at position %d in signature \"%s\"", c, i, signature)
+			}
+			return elems, i, nil
+		case '(', '[':
+			inner, end, err := compileSignatureElems(signature, i+1, depth+1)
+			if err != nil {
+				return nil, end, err
+			}
+
+			closing := byte(')')
+			elem.kind = sigGroup
+			if c == '[' {
+				closing = ']'
+				elem.kind = sigArray
+			}
+
+			if end >= len(signature) || signature[end] != closing {
+				return nil, i, OSCArgumentErrorf("unterminated '%c' at position %d in signature \"%s\"", c, i, signature)
+			}
+
+			elem.elems = inner
+			i = end
+		case '.':
+			elem.kind = sigAny
+		case '?', '*', '+':
+			return nil, i, OSCArgumentErrorf("'%c' at position %d in signature \"%s\" doesn't follow anything", c, i, signature)
+		case ',', ' ':
+			return nil, i, OSCArgumentErrorf("unexpected '%c' at position %d in signature \"%s\"", c, i, signature)
+		default:
+			elem.kind = sigTag
+			elem.tag = OSCTypeTag(c)
+		}
+		i++
+
+		elem.min, elem.max = 1, 1
+		if i < len(signature) {
+			switch signature[i] {
+			case '?':
+				elem.min, elem.max = 0, 1
+				i++
+			case '*':
+				elem.min, elem.max = 0, -1
+				i++
+			case '+':
+				elem.min, elem.max = 1, -1
+				i++
+			}
+		}
+
+		elems = append(elems, elem)
+	}
+
+	return elems, i, nil
+}
+
+func (s *Signature) String() string {
+	return s.source
+}
+
+// Match reports whether the arguments match the signature.
+func (s *Signature) Match(args []OSCArg) bool {
+	return matchSignature(s.elems, args)
+}
+
+// Check returns a descriptive OSCArgumentError if the arguments don't match
+// the signature.
+func (s *Signature) Check(args []OSCArg) error {
+	if s.Match(args) {
+		return nil
+	}
+
+	return OSCArgumentErrorf("arguments \"%s\" don't match signature \"%s\"", Message{Args: args}.TypeTags(), s.source)
+}
+
+// Matches a sequence of elements against all of the arguments. Rather than
+// backtracking, which takes exponential time for nested repeats like "(i*)*",
+// this tracks the set of argument positions each element can reach, so the
+// time taken is polynomial in the number of arguments.
+func matchSignature(elems []signatureElem, args []OSCArg) bool {
+	from := make([]bool, len(args) + 1)
+	from[0] = true
+
+	return matchSequence(elems, args, from)[len(args)]
+}
+
+// Returns the positions in args that a sequence of elements can end at, when
+// starting at any of the positions in from.
+func matchSequence(elems []signatureElem, args []OSCArg, from []bool) []bool {
+	reach := from
+	for _, e := range elems {
+		reach = e.ends(args, reach)
+	}
+
+	return reach
+}
+
+// Returns the positions that the element (with its repetitions) can end at,
+// when starting at any of the positions in from.
+func (e signatureElem) ends(args []OSCArg, from []bool) []bool {
+	result := make([]bool, len(args) + 1)
+	current := from
+
+	for count := 0; ; count++ {
+		if count >= e.min {
+			for i, ok := range current {
+				result[i] = result[i] || ok
+			}
+		}
+
+		if e.max >= 0 && count == e.max {
+			break
+		}
+
+		next := e.once(args, current)
+
+		// Once the minimum is reached, a position that has already been
+		// reached has already been expanded, and with at least as many
+		// repetitions left; so only new positions need to be expanded.
+		any := false
+		for i, ok := range next {
+			if ok && count + 1 >= e.min && result[i] {
+				next[i] = false
+			}
+			any = any || next[i]
+		}
+
+		if !any {
+			break
+		}
+
+		current = next
+	}
+
+	return result
+}
+
+// Returns the positions that a single repetition of the element can end at,
+// when starting at any of the positions in from.
+func (e signatureElem) once(args []OSCArg, from []bool) []bool {
+	if e.kind == sigGroup {
+		return matchSequence(e.elems, args, from)
+	}
+
+	next := make([]bool, len(args) + 1)
+	for i, ok := range from {
+		if ok && i < len(args) && e.matchArg(args[i]) {
+			next[i+1] = true
+		}
+	}
+
+	return next
+}
+
+// Reports whether a single argument matches a (non-group) element.
+func (e signatureElem) matchArg(arg OSCArg) bool {
+	if arg == nil {
+		return false
+	}
+
+	switch e.kind {
+	case sigAny:
+		return true
+	case sigArray:
+		array, ok := arg.(OSCArray)
+		return ok && matchSignature(e.elems, array)
+	case sigTag:
+		return arg.Tag() == e.tag
+	}
+
+	return false
+}
+
+// A SignatureRegistry holds the expected signature for each address of an
+// application, so that received messages can be checked against them in one
+// place:
+//
+//	signatures.Register("/mixer/fader", ",if")
+//	...
+//	address, args, err := ReadMessage(in)
+//	if err == nil {
+//		err = signatures.Check(address, args)
+//	}
+//
+// A SignatureRegistry is safe for concurrent use.
+type SignatureRegistry struct {
+	mu         sync.RWMutex
+	signatures map[OSCAddress]*Signature
+}
+
+func NewSignatureRegistry() *SignatureRegistry {
+	return &SignatureRegistry{signatures: make(map[OSCAddress]*Signature)}
+}
+
+// Register sets the signature expected for a concrete address, replacing any
+// previously registered signature.
+func (r *SignatureRegistry) Register(address OSCAddress, signature string) error {
+	if err := address.Valid(); err != nil {
+		return err
+	}
+
+	s, err := CompileSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.signatures[address] = s
+	return nil
+}
+
+// Lookup returns the signature registered for an address, if any.
+func (r *SignatureRegistry) Lookup(address OSCAddress) (*Signature, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.signatures[address]
+	return s, ok
+}
+
+// Check returns an OSCArgumentError if no signature is registered for the
+// address, or if the arguments don't match it.
+func (r *SignatureRegistry) Check(address OSCAddressPattern, args []OSCArg) error {
+	s, ok := r.Lookup(OSCAddress(address))
+	if !ok {
+		return OSCArgumentErrorf("%s: no signature registered for address", address)
+	}
+
+	if err := s.Check(args); err != nil {
+		return OSCArgumentErrorf("%s: %w", address, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package gosc
+
+import (
+	"path/filepath"
+	"runtime"
+	. "testing"
+	"time"
+)
+
+func expectSignatureMatch(t *T, signature string, args []OSCArg, expected bool) {
+	if MustCompileSignature(signature).Match(args) != expected {
+		if _, fname, line, ok := runtime.Caller(1); ok {
+			t.Errorf("%s:%d: expected signature \"%s\" matching %v to be %v", filepath.Base(fname), line, signature, args, expected)
+		} else {
+			t.Errorf("expected signature \"%s\" matching %v to be %v", signature, args, expected)
+		}
+	}
+}
+
+func TestSignatureMatch(t *T) {
+	i, f, s := OSCInt32(1), OSCFloat32(1), OSCString("s")
+
+	expectSignatureMatch(t, ",iff", []OSCArg{i, f, f}, true)
+	expectSignatureMatch(t, "iff", []OSCArg{i, f, f}, true)
+	expectSignatureMatch(t, ",iff", []OSCArg{i, f}, false)
+	expectSignatureMatch(t, ",iff", []OSCArg{i, f, f, f}, false)
+	expectSignatureMatch(t, ",iff", []OSCArg{f, f, f}, false)
+	expectSignatureMatch(t, ",", nil, true)
+	expectSignatureMatch(t, ",", []OSCArg{i}, false)
+
+	expectSignatureMatch(t, ",s*", nil, true)
+	expectSignatureMatch(t, ",s*", []OSCArg{s, s, s}, true)
+	expectSignatureMatch(t, ",s*", []OSCArg{s, i}, false)
+	expectSignatureMatch(t, ",s+", nil, false)
+	expectSignatureMatch(t, ",s+", []OSCArg{s}, true)
+	expectSignatureMatch(t, ",if?", []OSCArg{i}, true)
+	expectSignatureMatch(t, ",if?", []OSCArg{i, f}, true)
+	expectSignatureMatch(t, ",if?", []OSCArg{i, f, f}, false)
+
+	// Repeats that have to leave arguments for what follows.
+	expectSignatureMatch(t, ",i*i", []OSCArg{i, i, i}, true)
+	expectSignatureMatch(t, ",f*ff", []OSCArg{f, f}, true)
+	expectSignatureMatch(t, ",.*s", []OSCArg{i, f, s}, true)
+	expectSignatureMatch(t, ",.*s", []OSCArg{i, f}, false)
+
+	// Groups and arrays.
+	expectSignatureMatch(t, ",s(if)*", []OSCArg{s, i, f, i, f}, true)
+	expectSignatureMatch(t, ",s(if)*", []OSCArg{s, i, f, i}, false)
+	expectSignatureMatch(t, ",(i?)*f", []OSCArg{f}, true)
+	expectSignatureMatch(t, ",(ii)+", []OSCArg{i, i, i, i}, true)
+	expectSignatureMatch(t, ",(ii)+", []OSCArg{i, i, i}, false)
+	expectSignatureMatch(t, ",(ii)+", nil, false)
+	expectSignatureMatch(t, ",(if)?s", []OSCArg{i, f, s}, true)
+	expectSignatureMatch(t, ",(if)?s", []OSCArg{i, f, i, f, s}, false)
+	expectSignatureMatch(t, ",s[i*]", []OSCArg{s, OSCArray{i, i}}, true)
+	expectSignatureMatch(t, ",s[i*]", []OSCArg{s, OSCArray{f}}, false)
+	expectSignatureMatch(t, ",s[i*]", []OSCArg{s, i}, false)
+
+	// Bools are matched by their tag.
+	expectSignatureMatch(t, ",T", []OSCArg{OSCBool(true)}, true)
+	expectSignatureMatch(t, ",T", []OSCArg{OSCBool(false)}, false)
+	expectSignatureMatch(t, ",.", []OSCArg{OSCBool(false)}, true)
+}
+
+// The arguments come from the network, so matching must not blow up with
+// their number (as naive backtracking does with nested repeats).
+func TestSignatureNestedRepeats(t *T) {
+	args := make([]OSCArg, 40)
+	for n := range args {
+		args[n] = OSCInt32(n)
+	}
+
+	start := time.Now()
+	expectSignatureMatch(t, ",(i*)*f", args, false)
+	expectSignatureMatch(t, ",((i*)*(i?)+)*i*f", args, false)
+	expectSignatureMatch(t, ",(i*)*f", append(args, OSCFloat32(1)), true)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("matching took %s", elapsed)
+	}
+}
+
+func TestCompileSignatureErrors(t *T) {
+	_, err := CompileSignature(",i(f")
+	expectArgumentError(t, err, "unterminated '(' at position 2")
+
+	_, err = CompileSignature(",i[f)")
+	expectArgumentError(t, err, "unterminated '[' at position 2")
+
+	_, err = CompileSignature(",if]")
+	expectArgumentError(t, err, "unmatched ']' at position 3")
+
+	_, err = CompileSignature(",*i")
+	expectArgumentError(t, err, "'*' at position 1")
+
+	_, err = CompileSignature(",i,f")
+	expectArgumentError(t, err, "unexpected ',' at position 2")
+}
+
+func TestSignatureRegistry(t *T) {
+	r := NewSignatureRegistry()
+	expectNil(t, r.Register(OSCAddress("/mixer/fader"), ",if"))
+
+	expectNil(t, r.Check(OSCAddressPattern("/mixer/fader"), []OSCArg{OSCInt32(1), OSCFloat32(0.5)}))
+
+	err := r.Check(OSCAddressPattern("/mixer/fader"), []OSCArg{OSCInt32(1), OSCInt32(2)})
+	expectArgumentError(t, err, "/mixer/fader: arguments \",ii\" don't match signature \",if\"")
+
+	err = r.Check(OSCAddressPattern("/mixer/mute"), nil)
+	expectArgumentError(t, err, "/mixer/mute: no signature registered")
+
+	expectArgumentError(t, r.Register(OSCAddress("/mixer/*"), ",i"), "")
+	expectArgumentError(t, r.Register(OSCAddress("/mixer/mute"), ",i)"), "unmatched ')'")
+
+	s, ok := r.Lookup(OSCAddress("/mixer/fader"))
+	expectSame(t, true, ok)
+	expectSame(t, ",if", s.String())
+}
@@ -0,0 +1,201 @@
+package gosc
+
+import (
+	"bufio"
+	"io"
+)
+
+// Special bytes used by SLIP framing (RFC 1055), as recommended by OSC 1.1 for
+// stream-based transports.
+const (
+	SLIP_END     = 0xc0
+	SLIP_ESC     = 0xdb
+	SLIP_ESC_END = 0xdc
+	SLIP_ESC_ESC = 0xdd
+)
+
+// Writes SLIP-framed packets to an underlying stream. Bytes written are
+// collected into the current frame; nothing is sent until Flush is called,
+// which writes the whole frame (stuffed, and with an END byte on either side)
+// in a single write. For example:
+//
+//	w := NewSLIPWriter(conn)
+//	WriteMessage(w, "/led", OSCInt32(1))
+//	w.Flush()
+type SLIPWriter struct {
+	out   io.Writer
+	frame []byte
+	buf   []byte
+}
+
+func NewSLIPWriter(out io.Writer) *SLIPWriter {
+	return &SLIPWriter{out: out}
+}
+
+// Write adds data to the current frame. It never fails.
+func (w *SLIPWriter) Write(p []byte) (int, error) {
+	w.frame = append(w.frame, p...)
+	return len(p), nil
+}
+
+// Flush sends the current frame, then starts a new (empty) one.
+func (w *SLIPWriter) Flush() error {
+	buf := append(w.buf[:0], SLIP_END)
+
+	for _, b := range w.frame {
+		switch b {
+		case SLIP_END:
+			buf = append(buf, SLIP_ESC, SLIP_ESC_END)
+		case SLIP_ESC:
+			buf = append(buf, SLIP_ESC, SLIP_ESC_ESC)
+		default:
+			buf = append(buf, b)
+		}
+	}
+
+	buf = append(buf, SLIP_END)
+	w.buf = buf
+	w.frame = w.frame[:0]
+
+	_, err := w.out.Write(buf)
+	return err
+}
+
+// WriteFrame sends the data as a complete frame, along with anything already
+// written to the current frame.
+func (w *SLIPWriter) WriteFrame(p []byte) error {
+	w.Write(p)
+	return w.Flush()
+}
+
+// Reads SLIP-framed packets from an underlying stream. Read returns the
+// (unstuffed) contents of the current frame, and io.EOF at the end of the
+// frame; Next advances to the following frame. For example:
+//
+//	r := NewSLIPReader(conn)
+//	for r.Next() == nil {
+//		address, args, err := ReadMessage(r)
+//		...
+//	}
+//
+// Empty frames (from double-END framing) are skipped. If a frame is corrupt
+// (an invalid escape sequence), Read returns an error and Next resynchronizes
+// on the next END byte.
+type SLIPReader struct {
+	in    *bufio.Reader
+	state slipState
+}
+
+type slipState int
+
+const (
+	// Between frames; the next Read starts a new frame.
+	slipBetween slipState = iota
+	// Inside a frame.
+	slipInFrame
+	// At the end of a frame; Read returns io.EOF until Next is called.
+	slipEnded
+)
+
+func NewSLIPReader(in io.Reader) *SLIPReader {
+	return &SLIPReader{in: bufio.NewReader(in)}
+}
+
+// Next discards the rest of the current frame (if any), and advances to the
+// start of the next non-empty frame. Returns io.EOF at the end of the stream.
+func (r *SLIPReader) Next() error {
+	// Discard up to and including the END byte of the current frame.
+	if r.state == slipInFrame {
+		if _, err := r.in.ReadBytes(SLIP_END); err != nil {
+			return err
+		}
+	}
+
+	// Skip over empty frames.
+	for {
+		b, err := r.in.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		if b != SLIP_END {
+			r.in.UnreadByte()
+			r.state = slipInFrame
+			return nil
+		}
+	}
+}
+
+// Read reads from the current frame, starting a new frame first if the last
+// one has been finished with Next. Returns io.EOF at the end of the frame.
+func (r *SLIPReader) Read(p []byte) (int, error) {
+	if r.state == slipBetween {
+		if err := r.Next(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.state == slipEnded {
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) {
+		b, err := r.in.ReadByte()
+		if err == io.EOF {
+			// The stream ended without a final END byte; treat it as the end
+			// of the frame.
+			r.state = slipEnded
+			break
+		} else if err != nil {
+			return n, err
+		}
+
+		switch b {
+		case SLIP_END:
+			r.state = slipEnded
+			return r.result(n)
+		case SLIP_ESC:
+			esc, err := r.in.ReadByte()
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+
+			switch {
+			case err == nil && esc == SLIP_ESC_END:
+				b = SLIP_END
+			case err == nil && esc == SLIP_ESC_ESC:
+				b = SLIP_ESC
+			default:
+				// Leave an END byte in place, so that Next doesn't skip the
+				// following frame while resynchronizing.
+				if err == nil && esc == SLIP_END {
+					r.in.UnreadByte()
+				}
+				return n, OSCReadErrorf("invalid SLIP escape sequence 0x%02x 0x%02x", SLIP_ESC, esc)
+			}
+		}
+
+		p[n] = b
+		n++
+	}
+
+	return r.result(n)
+}
+
+func (r *SLIPReader) result(n int) (int, error) {
+	if n == 0 && r.state == slipEnded {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// ReadFrame reads the entire next frame.
+func (r *SLIPReader) ReadFrame() ([]byte, error) {
+	if err := r.Next(); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
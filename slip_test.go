@@ -0,0 +1,100 @@
+package gosc
+
+import (
+	"bytes"
+	"io"
+	. "testing"
+)
+
+func TestSLIPWriter(t *T) {
+	var out bytes.Buffer
+	w := NewSLIPWriter(&out)
+
+	w.Write([]byte{1, SLIP_END, 2})
+	w.Write([]byte{SLIP_ESC, 3})
+	expectSame(t, 0, out.Len())
+
+	expectNil(t, w.Flush())
+	expectSame(t, []byte{SLIP_END, 1, SLIP_ESC, SLIP_ESC_END, 2, SLIP_ESC, SLIP_ESC_ESC, 3, SLIP_END}, out.Bytes())
+	out.Reset()
+
+	expectNil(t, w.WriteFrame([]byte{4}))
+	expectSame(t, []byte{SLIP_END, 4, SLIP_END}, out.Bytes())
+}
+
+func TestSLIPReader(t *T) {
+	r := NewSLIPReader(bytes.NewReader([]byte{
+		SLIP_END, 1, SLIP_ESC, SLIP_ESC_END, 2, SLIP_ESC, SLIP_ESC_ESC, 3, SLIP_END,
+		SLIP_END, SLIP_END, // empty frames
+		4, 5, SLIP_END,
+		6, // no final END
+	}))
+
+	frame, err := r.ReadFrame()
+	expectNil(t, err)
+	expectSame(t, []byte{1, SLIP_END, 2, SLIP_ESC, 3}, frame)
+
+	frame, err = r.ReadFrame()
+	expectNil(t, err)
+	expectSame(t, []byte{4, 5}, frame)
+
+	frame, err = r.ReadFrame()
+	expectNil(t, err)
+	expectSame(t, []byte{6}, frame)
+
+	_, err = r.ReadFrame()
+	expectSame(t, io.EOF, err)
+}
+
+func TestSLIPMessages(t *T) {
+	var stream bytes.Buffer
+	w := NewSLIPWriter(&stream)
+
+	messages := []Message{
+		{OSCAddressPattern("/a"), []OSCArg{OSCInt32(SLIP_END), OSCBlob([]byte{SLIP_ESC, SLIP_END, 0, 0})}},
+		{OSCAddressPattern("/b"), []OSCArg{OSCString("x")}},
+	}
+
+	for _, m := range messages {
+		_, err := WriteMessage(w, m.Address, m.Args...)
+		expectNil(t, err)
+		expectNil(t, w.Flush())
+	}
+
+	r := NewSLIPReader(&stream)
+	var read []Message
+	for r.Next() == nil {
+		address, args, err := ReadMessage(r)
+		expectNil(t, err)
+		read = append(read, Message{address, args})
+	}
+
+	expectSame(t, messages, read)
+}
+
+func TestSLIPReaderResync(t *T) {
+	var stream bytes.Buffer
+	w := NewSLIPWriter(&stream)
+
+	WriteMessage(w, OSCAddressPattern("/first"))
+	w.Flush()
+	stream.Write([]byte{SLIP_END, '/', 'x', SLIP_ESC, 0x01, 0, 0, 0, SLIP_END}) // bad escape
+	stream.Write([]byte{SLIP_END, '/', 'y', SLIP_ESC, SLIP_END})                // escaped END
+	WriteMessage(w, OSCAddressPattern("/last"))
+	w.Flush()
+
+	r := NewSLIPReader(&stream)
+	var addresses []OSCAddressPattern
+	var errors int
+	for r.Next() == nil {
+		address, _, err := ReadMessage(r)
+		if err != nil {
+			errors++
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+
+	expectSame(t, []OSCAddressPattern{"/first", "/last"}, addresses)
+	expectSame(t, 2, errors)
+}
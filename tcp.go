@@ -0,0 +1,367 @@
+package gosc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// Default limit on the size of a single size-prefixed packet. Larger sizes are
+// rejected before anything is allocated.
+const OSC_MAX_FRAME_SIZE = 1 << 20
+
+// Writes a message to a stream, preceded by its size as an int32, as specified
+// by OSC 1.0 for stream-based transports such as TCP. Returns the total number
+// of bytes sent, including the size prefix.
+func WriteFramedMessage(out io.Writer, address OSCAddressPattern, args...OSCArg) (int, error) {
+	return WriteFramedPacket(out, Message{address, args})
+}
+
+// Writes a message or bundle to a stream, preceded by its size as an int32.
+func WriteFramedPacket(out io.Writer, packet OSCPacket) (int, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 0})
+
+	if _, err := packet.WriteTo(&buf); err != nil {
+		return 0, err
+	}
+
+	// The whole frame goes out in a single write, so frames from concurrent
+	// writers can't interleave.
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data) - 4))
+	return out.Write(data)
+}
+
+// Reads a size-prefixed message from a stream (see WriteFramedMessage).
+// Returns an error if the declared size doesn't match the size of the message
+// actually read; the whole frame is consumed either way, so the stream remains
+// usable after a malformed message.
+func ReadFramedMessage(in io.Reader) (OSCAddressPattern, []OSCArg, error) {
+	m, err := readFramedMessage(in, nil, OSC_MAX_FRAME_SIZE)
+	return m.Address, m.Args, err
+}
+
+// Reads a size-prefixed frame from a stream, returning its contents without
+// decoding them. Frames larger than OSC_MAX_FRAME_SIZE are rejected. Returns
+// io.EOF if the stream ends cleanly before the frame starts.
+func ReadFrame(in io.Reader) ([]byte, error) {
+	return readFrame(in, nil, OSC_MAX_FRAME_SIZE)
+}
+
+// Reads a single frame into buf (which is grown as needed) and decodes the
+// message in it.
+func readFramedMessage(in io.Reader, buf *[]byte, max int) (Message, error) {
+	frame, err := readFrame(in, buf, max)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return decodeFrame(frame, DecodeOptions{}.effective())
+}
+
+// Decodes the message in a frame, which must use every byte of the frame.
+func decodeFrame(frame []byte, opts DecodeOptions) (Message, error) {
+	r := bytes.NewReader(frame)
+	m, err := readMessageWith(r, opts)
+	if err != nil {
+		return m, err
+	}
+
+	if r.Len() != 0 {
+		return m, OSCReadErrorf("frame declared %d bytes, but the message used %d", len(frame), len(frame) - r.Len())
+	}
+
+	return m, nil
+}
+
+// Decodes the message or bundle in a frame, which must use every byte of the
+// frame.
+func decodeFramedPacket(frame []byte, opts DecodeOptions) (OSCPacket, error) {
+	r := bytes.NewReader(frame)
+	packet, err := readPacket(r, 0, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() != 0 {
+		return nil, OSCReadErrorf("frame declared %d bytes, but the message used %d", len(frame), len(frame) - r.Len())
+	}
+
+	return packet, nil
+}
+
+// Reads a size-prefixed frame, returning its contents. Returns io.EOF if the
+// stream ends cleanly before the frame starts.
+func readFrame(in io.Reader, buf *[]byte, max int) ([]byte, error) {
+	var size int32
+	if err := binary.Read(in, binary.BigEndian, &size); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, OSCReadErrorf("failed to read frame size: %w", err)
+	}
+
+	if size < 0 {
+		return nil, OSCReadErrorf("invalid frame size %d", size)
+	} else if int(size) > max {
+		return nil, OSCReadErrorf("invalid frame size %d (maximum is %d)", size, max).withKind(ErrTooLarge)
+	}
+
+	var frame []byte
+	if buf != nil && cap(*buf) >= int(size) {
+		frame = (*buf)[:size]
+	} else {
+		frame = make([]byte, size)
+		if buf != nil {
+			*buf = frame
+		}
+	}
+
+	if _, err := io.ReadFull(in, frame); err != nil {
+		return nil, OSCReadErrorf("failed to read %d byte frame: %w", size, err)
+	}
+
+	return frame, nil
+}
+
+// Sends (and receives) size-prefixed OSC packets over a stream connection,
+// usually TCP.
+//
+// Sending is safe for concurrent use; receiving is not.
+type TCPClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	buf  []byte
+}
+
+// DialTCP connects to the address (e.g. "localhost:9000").
+func DialTCP(address string) (*TCPClient, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTCPClient(conn), nil
+}
+
+// NewTCPClient wraps an existing stream connection (which doesn't have to be
+// TCP; a unix socket works just as well).
+func NewTCPClient(conn net.Conn) *TCPClient {
+	return &TCPClient{conn: conn}
+}
+
+// Send writes a size-prefixed message to the connection.
+func (c *TCPClient) Send(address OSCAddressPattern, args...OSCArg) error {
+	return c.SendPacket(Message{address, args})
+}
+
+// SendPacket writes a size-prefixed message or bundle to the connection.
+func (c *TCPClient) SendPacket(packet OSCPacket) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := WriteFramedPacket(c.conn, packet)
+	return err
+}
+
+// Receive reads the next size-prefixed message from the connection.
+func (c *TCPClient) Receive() (Message, error) {
+	return readFramedMessage(c.conn, &c.buf, OSC_MAX_FRAME_SIZE)
+}
+
+func (c *TCPClient) Close() error {
+	return c.conn.Close()
+}
+
+// Receives size-prefixed OSC packets over TCP. Each accepted connection is
+// served by its own goroutine, with its own handler.
+type TCPServer struct {
+	// Addr is the local address to listen on (e.g. ":9000"), used by
+	// ListenAndServe.
+	Addr string
+
+	// NewHandler is called for each accepted connection, and returns the
+	// handler for the messages received on it. Returning nil rejects (closes)
+	// the connection. Messages in bundles are passed to the handler one by
+	// one, in order, and their timetags are ignored.
+	NewHandler func(conn net.Conn) func(msg Message)
+
+	// NewPacketHandler, if set, is used in place of NewHandler, and returns a
+	// handler for every packet (either a Message or an OSCBundle) received on
+	// the connection.
+	NewPacketHandler func(conn net.Conn) func(packet OSCPacket)
+
+	// ErrorHandler, if set, is called for every error on a connection. Messages
+	// that can't be decoded are skipped; errors reading the stream itself
+	// close the connection. A connection closed cleanly by the remote end is
+	// not reported.
+	ErrorHandler func(err error, conn net.Conn)
+
+	// MaxPacketSize limits the size of a single frame. Defaults to
+	// OSC_MAX_FRAME_SIZE.
+	MaxPacketSize int
+
+	// Decode sets the options for decoding frames, including the registry of
+	// custom types and the largest blob to accept.
+	Decode DecodeOptions
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// ListenAndServe listens on s.Addr and serves incoming connections until the
+// context is cancelled.
+func (s *TCPServer) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections from the listener until the context is cancelled
+// (returning the context's error) or the listener fails. When Serve returns,
+// the listener and all open connections have been closed, and all handlers
+// have returned.
+func (s *TCPServer) Serve(ctx context.Context, ln net.Listener) error {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		ln.Close()
+		s.closeAll()
+	}()
+
+	defer s.wg.Wait()
+	defer close(done)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		handler := s.newHandler(conn)
+		if handler == nil {
+			conn.Close()
+			continue
+		}
+
+		s.track(conn)
+		s.wg.Add(1)
+		go s.serveConn(conn, handler)
+	}
+}
+
+// Returns the packet handler for a connection, or nil to reject it.
+func (s *TCPServer) newHandler(conn net.Conn) func(OSCPacket) {
+	if s.NewPacketHandler != nil {
+		return s.NewPacketHandler(conn)
+	}
+
+	if s.NewHandler == nil {
+		return nil
+	}
+
+	handler := s.NewHandler(conn)
+	if handler == nil {
+		return nil
+	}
+
+	return func(packet OSCPacket) {
+		eachMessage(packet, handler)
+	}
+}
+
+func (s *TCPServer) serveConn(conn net.Conn, handler func(OSCPacket)) {
+	defer s.wg.Done()
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	max := s.MaxPacketSize
+	if max <= 0 {
+		max = OSC_MAX_FRAME_SIZE
+	}
+
+	opts := s.Decode.effective()
+
+	var buf []byte
+	for {
+		frame, err := readFrame(conn, &buf, max)
+		if err == io.EOF || s.isClosed() {
+			return
+		} else if err != nil {
+			s.reportError(err, conn)
+			return
+		}
+
+		// The frame has been read in full, so a bad packet doesn't affect
+		// the ones after it.
+		packet, err := decodeFramedPacket(frame, opts)
+		if err != nil {
+			s.reportError(err, conn)
+			continue
+		}
+
+		handler(packet)
+	}
+}
+
+func (s *TCPServer) reportError(err error, conn net.Conn) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err, conn)
+	}
+}
+
+func (s *TCPServer) track(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A connection accepted just as the server shuts down is closed right
+	// away, so that its handler returns.
+	if s.closed {
+		conn.Close()
+		return
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+}
+
+func (s *TCPServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn)
+}
+
+func (s *TCPServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+func (s *TCPServer) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
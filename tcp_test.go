@@ -0,0 +1,261 @@
+package gosc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	. "testing"
+	"time"
+)
+
+func TestWriteFramedMessage(t *T) {
+	var out bytes.Buffer
+
+	n, err := WriteFramedMessage(&out, OSCAddressPattern("/a"), OSCInt32(5))
+	expectNil(t, err)
+	expectSame(t, 16, n)
+	expectSame(t,
+		[]byte{0,0,0,12,   // size
+		       47,97,0,0,  // "/a"
+		       44,105,0,0, // ",i"
+		       0,0,0,5},
+		out.Bytes())
+}
+
+func TestReadFramedMessage(t *T) {
+	var in bytes.Buffer
+
+	WriteFramedMessage(&in, OSCAddressPattern("/a"), OSCInt32(5))
+	WriteFramedMessage(&in, OSCAddressPattern("/b"), OSCString("x"))
+
+	address, args, err := ReadFramedMessage(&in)
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/a"), address)
+	expectSame(t, []OSCArg{OSCInt32(5)}, args)
+
+	address, args, err = ReadFramedMessage(&in)
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/b"), address)
+	expectSame(t, []OSCArg{OSCString("x")}, args)
+}
+
+func TestReadFrame(t *T) {
+	in := bytes.NewBuffer([]byte{
+		0,0,0,4, 1,2,3,4, // not a message, but frames aren't decoded
+		0,0,0,0,          // an empty frame
+	})
+
+	frame, err := ReadFrame(in)
+	expectNil(t, err)
+	expectSame(t, []byte{1,2,3,4}, frame)
+
+	frame, err = ReadFrame(in)
+	expectNil(t, err)
+	expectSame(t, []byte{}, frame)
+
+	_, err = ReadFrame(in)
+	expectSame(t, io.EOF, err)
+
+	_, err = ReadFrame(bytes.NewBuffer([]byte{0,0x10,0,1}))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %#v", err)
+	}
+}
+
+func TestReadFramedMessageSizeMismatch(t *T) {
+	in := bytes.NewBuffer([]byte{
+		0,0,0,12, 47,97,0,0, 44,0,0,0, 0,0,0,0, // declares 4 more bytes than the message uses
+		0,0,0,8, 47,98,0,0, 44,0,0,0,          // a valid message after it
+	})
+
+	_, _, err := ReadFramedMessage(in)
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError, got %#v", err)
+	}
+
+	// The rest of the bad frame was consumed, so the next message is fine.
+	address, _, err := ReadFramedMessage(in)
+	expectNil(t, err)
+	expectSame(t, OSCAddressPattern("/b"), address)
+
+	// A frame too small for the message it contains.
+	_, _, err = ReadFramedMessage(bytes.NewBuffer([]byte{0,0,0,4, 47,97,0,0, 44,0,0,0}))
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError, got %#v", err)
+	}
+
+	// Negative and oversized frames.
+	_, _, err = ReadFramedMessage(bytes.NewBuffer([]byte{0xff,0xff,0xff,0xfc}))
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError, got %#v", err)
+	}
+	_, _, err = ReadFramedMessage(bytes.NewBuffer([]byte{0x7f,0xff,0xff,0xfc}))
+	if _, ok := err.(OSCReadError); !ok {
+		t.Errorf("expected an OSCReadError, got %#v", err)
+	}
+}
+
+func TestTCPClientServer(t *T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	type received struct {
+		conn int
+		msg  Message
+	}
+	messages := make(chan received, 10)
+	errors := make(chan error, 10)
+	connCount := 0
+
+	server := &TCPServer{
+		NewHandler: func(conn net.Conn) func(Message) {
+			connCount++
+			id := connCount
+			return func(m Message) {
+				messages <- received{id, m}
+			}
+		},
+		ErrorHandler: func(err error, conn net.Conn) {
+			errors <- err
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- server.Serve(ctx, ln)
+	}()
+
+	first, err := DialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer first.Close()
+
+	expectNil(t, first.Send(OSCAddressPattern("/one"), OSCInt32(1)))
+
+	select {
+	case r := <-messages:
+		expectSame(t, received{1, Message{OSCAddressPattern("/one"), []OSCArg{OSCInt32(1)}}}, r)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for message")
+	}
+
+	second, err := DialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer second.Close()
+
+	// A malformed frame is reported, but doesn't close the connection.
+	second.conn.Write([]byte{0,0,0,4, 0,0,0,0})
+	expectNil(t, second.Send(OSCAddressPattern("/two")))
+
+	select {
+	case <-errors:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for error")
+	}
+
+	select {
+	case r := <-messages:
+		expectSame(t, received{2, Message{OSCAddressPattern("/two"), []OSCArg{}}}, r)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for message")
+	}
+
+	cancel()
+
+	select {
+	case err := <-result:
+		expectSame(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatalf("server did not stop after cancellation")
+	}
+
+	// Open connections are closed when the server stops.
+	first.conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := first.Receive(); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+}
+
+func TestTCPServerBundles(t *T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	packets := make(chan OSCPacket, 10)
+	messages := make(chan Message, 10)
+
+	server := &TCPServer{
+		NewHandler: func(conn net.Conn) func(Message) {
+			return func(m Message) {
+				messages <- m
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Serve(ctx, ln)
+
+	client, err := DialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer client.Close()
+
+	bundle := OSCBundle{
+		Timetag: OSC_TIMETAG_IMMEDIATELY,
+		Elements: []OSCPacket{
+			Message{OSCAddressPattern("/a"), []OSCArg{OSCInt32(1)}},
+			Message{OSCAddressPattern("/b"), []OSCArg{}},
+		},
+	}
+	expectNil(t, client.SendPacket(bundle))
+
+	for _, expected := range []OSCAddressPattern{"/a", "/b"} {
+		select {
+		case m := <-messages:
+			expectSame(t, expected, m.Address)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+
+	// With NewPacketHandler, the bundle is passed on whole.
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	server = &TCPServer{
+		NewPacketHandler: func(conn net.Conn) func(OSCPacket) {
+			return func(p OSCPacket) {
+				packets <- p
+			}
+		},
+	}
+	go server.Serve(ctx, ln)
+
+	other, err := DialTCP(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer other.Close()
+
+	expectNil(t, other.SendPacket(bundle))
+
+	select {
+	case p := <-packets:
+		expectSame(t, OSCPacket(bundle), p)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for bundle")
+	}
+}
@@ -0,0 +1,119 @@
+package gosc
+
+import (
+	"time"
+)
+
+// The special timetag value meaning "immediately": 63 zero bits followed by a
+// one in the least significant bit.
+const OSC_TIMETAG_IMMEDIATELY = OSCTimetag(1)
+
+// Number of seconds between the NTP epoch (1900-01-01) and the Unix epoch
+// (1970-01-01).
+const ntpEpochOffset = 2208988800
+
+const nanosPerSecond = uint64(time.Second)
+
+// Constructs a timetag from NTP seconds (since 1900) and fractional seconds
+// (in units of 1/2^32 of a second).
+func NewOSCTimetag(seconds, fraction uint32) OSCTimetag {
+	return OSCTimetag(uint64(seconds) << 32 | uint64(fraction))
+}
+
+// Converts a time to the equivalent NTP timetag. The zero time converts to
+// OSC_TIMETAG_IMMEDIATELY. Times after the first NTP era (which ends in early
+// 2036) wrap around, as they do in NTP itself; Time converts them back
+// correctly for times from 1968 to 2104.
+//
+// Fractions are rounded up, so that converting the result back with Time
+// gives exactly the original time (to the nanosecond).
+func OSCTimetagFromTime(t time.Time) OSCTimetag {
+	if t.IsZero() {
+		return OSC_TIMETAG_IMMEDIATELY
+	}
+
+	seconds := uint32(t.Unix() + ntpEpochOffset)
+	fraction := (uint64(t.Nanosecond()) << 32 + nanosPerSecond - 1) / nanosPerSecond
+
+	return NewOSCTimetag(seconds, uint32(fraction))
+}
+
+// Seconds returns the whole seconds since 1900 (the upper 32 bits).
+func (t OSCTimetag) Seconds() uint32 {
+	return uint32(t >> 32)
+}
+
+// Fraction returns the fractional seconds, in units of 1/2^32 of a second
+// (the lower 32 bits).
+func (t OSCTimetag) Fraction() uint32 {
+	return uint32(t)
+}
+
+// IsImmediate returns true if the timetag is the special "immediately" value.
+func (t OSCTimetag) IsImmediate() bool {
+	return t == OSC_TIMETAG_IMMEDIATELY
+}
+
+// Time converts the timetag to a UTC time, truncated to the nanosecond.
+// OSC_TIMETAG_IMMEDIATELY converts to the zero time.
+//
+// As in RFC 4330, timetags with the most significant bit set are taken to be
+// in the first NTP era (1968 to 2036), and those with it clear in the second
+// (2036 to 2104), so that timetags keep working after the first era ends.
+func (t OSCTimetag) Time() time.Time {
+	if t.IsImmediate() {
+		return time.Time{}
+	}
+
+	seconds := int64(t.Seconds()) - ntpEpochOffset
+	if t.Seconds() & 0x80000000 == 0 {
+		seconds += 1 << 32
+	}
+	nanos := uint64(t.Fraction()) * nanosPerSecond >> 32
+
+	return time.Unix(seconds, int64(nanos)).UTC()
+}
+
+// Before returns true if t is earlier than u.
+func (t OSCTimetag) Before(u OSCTimetag) bool {
+	return t < u
+}
+
+// After returns true if t is later than u.
+func (t OSCTimetag) After(u OSCTimetag) bool {
+	return t > u
+}
+
+// Add returns the timetag t+d, rounded to the nearest 1/2^32 of a second.
+func (t OSCTimetag) Add(d time.Duration) OSCTimetag {
+	if d < 0 {
+		return t - OSCTimetag(durationToNTP(-d))
+	} else {
+		return t + OSCTimetag(durationToNTP(d))
+	}
+}
+
+// Sub returns the duration t-u, truncated to the nanosecond.
+func (t OSCTimetag) Sub(u OSCTimetag) time.Duration {
+	if t < u {
+		return -ntpToDuration(uint64(u - t))
+	} else {
+		return ntpToDuration(uint64(t - u))
+	}
+}
+
+// Converts a (non-negative) duration to NTP fixed-point units.
+func durationToNTP(d time.Duration) uint64 {
+	seconds := uint64(d / time.Second)
+	nanos := uint64(d % time.Second)
+
+	return seconds << 32 + (nanos << 32 + nanosPerSecond / 2) / nanosPerSecond
+}
+
+// Converts NTP fixed-point units to a (non-negative) duration.
+func ntpToDuration(v uint64) time.Duration {
+	seconds := v >> 32
+	nanos := (v & 0xffffffff) * nanosPerSecond >> 32
+
+	return time.Duration(seconds) * time.Second + time.Duration(nanos)
+}
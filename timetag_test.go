@@ -0,0 +1,88 @@
+package gosc
+
+import (
+	. "testing"
+	"time"
+)
+
+func TestOSCTimetagFromTime(t *T) {
+	epoch := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectSame(t, OSCTimetag(0), OSCTimetagFromTime(epoch))
+
+	unix := time.Unix(0, 0)
+	expectSame(t, NewOSCTimetag(2208988800, 0), OSCTimetagFromTime(unix))
+
+	half := time.Unix(1, int64(time.Second / 2))
+	expectSame(t, NewOSCTimetag(2208988801, 0x80000000), OSCTimetagFromTime(half))
+
+	// Times after the first NTP era wrap around.
+	era := time.Date(2036, 2, 7, 6, 28, 16, 0, time.UTC)
+	expectSame(t, NewOSCTimetag(0, 0), OSCTimetagFromTime(era))
+	expectSame(t, NewOSCTimetag(60, 0), OSCTimetagFromTime(era.Add(time.Minute)))
+
+	expectSame(t, OSC_TIMETAG_IMMEDIATELY, OSCTimetagFromTime(time.Time{}))
+}
+
+func TestOSCTimetagTime(t *T) {
+	expectSame(t, time.Unix(0, 0).UTC(), NewOSCTimetag(2208988800, 0).Time())
+	expectSame(t, time.Unix(1, int64(time.Second / 4)).UTC(), NewOSCTimetag(2208988801, 0x40000000).Time())
+	expectSame(t, true, OSC_TIMETAG_IMMEDIATELY.Time().IsZero())
+
+	// Timetags with the top bit clear are in the second NTP era.
+	expectSame(t, time.Date(2036, 2, 7, 6, 28, 16, 0, time.UTC), NewOSCTimetag(0, 0).Time())
+	expectSame(t, time.Date(2036, 2, 7, 6, 28, 17, 0, time.UTC), NewOSCTimetag(1, 0).Time())
+	expectSame(t, time.Date(1968, 1, 20, 3, 14, 8, 0, time.UTC), NewOSCTimetag(0x80000000, 0).Time())
+	expectSame(t, true, OSC_TIMETAG_IMMEDIATELY.IsImmediate())
+}
+
+func TestOSCTimetagRoundTrip(t *T) {
+	times := []time.Time{
+		time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC),
+		time.Date(2026, 10, 17, 12, 34, 56, 1, time.UTC),
+		time.Date(2026, 10, 17, 12, 34, 56, 999999999, time.UTC),
+		time.Date(1999, 12, 31, 23, 59, 59, 123456789, time.UTC),
+		time.Date(1968, 1, 20, 3, 14, 8, 500, time.UTC),
+
+		// Either side of the end of the first NTP era, and in the second.
+		time.Date(2036, 2, 7, 6, 28, 15, 999999999, time.UTC),
+		time.Date(2036, 2, 7, 6, 28, 16, 0, time.UTC),
+		time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2104, 2, 26, 9, 42, 23, 0, time.UTC),
+	}
+
+	for _, tm := range times {
+		expectSame(t, tm, OSCTimetagFromTime(tm).Time())
+	}
+
+	// Every fraction should survive a round trip through time.Time as well.
+	for _, frac := range []uint32{0, 1, 2, 0x7fffffff, 0x80000000, 0xfffffffe, 0xffffffff} {
+		tag := NewOSCTimetag(3900000000, frac)
+		tm := tag.Time()
+		back := OSCTimetagFromTime(tm)
+		expectSame(t, tm, back.Time())
+	}
+}
+
+func TestOSCTimetagComparison(t *T) {
+	a := NewOSCTimetag(100, 0)
+	b := NewOSCTimetag(100, 1)
+
+	expectSame(t, true, a.Before(b))
+	expectSame(t, false, b.Before(a))
+	expectSame(t, true, b.After(a))
+	expectSame(t, false, a.After(a))
+	expectSame(t, true, OSC_TIMETAG_IMMEDIATELY.Before(a))
+}
+
+func TestOSCTimetagAddSub(t *T) {
+	base := NewOSCTimetag(100, 0)
+
+	expectSame(t, NewOSCTimetag(101, 0), base.Add(time.Second))
+	expectSame(t, NewOSCTimetag(100, 0x80000000), base.Add(500 * time.Millisecond))
+	expectSame(t, NewOSCTimetag(98, 0x80000000), base.Add(-1500 * time.Millisecond))
+	expectSame(t, 1500 * time.Millisecond, NewOSCTimetag(101, 0x80000000).Sub(base))
+	expectSame(t, -2 * time.Second, NewOSCTimetag(98, 0).Sub(base))
+
+	tm := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectSame(t, tm.Add(90 * time.Minute), OSCTimetagFromTime(tm).Add(90 * time.Minute).Time())
+}
@@ -3,6 +3,8 @@ package gosc
 import (
 	"encoding/binary"
 	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 const OSC_BYTE_ALIGNMENT = 4
@@ -56,7 +58,7 @@ type OSCInt32 int32
 func ReadOSCInt32(in io.Reader) (OSCInt32, error) {
 	var out OSCInt32
 	if err := binary.Read(in, binary.BigEndian, &out); err != nil {
-		return 0, OSCReadErrorf("failed to read int32: %s", err)
+		return 0, OSCReadErrorf("failed to read int32: %w", err)
 	} else {
 		return out, nil
 	}
@@ -80,7 +82,7 @@ type OSCFloat32 float32
 func ReadOSCFloat32(in io.Reader) (OSCFloat32, error) {
 	var out OSCFloat32
 	if err := binary.Read(in, binary.BigEndian, &out); err != nil {
-		return 0, OSCReadErrorf("failed to read float32: %s", err)
+		return 0, OSCReadErrorf("failed to read float32: %w", err)
 	} else {
 		return out, nil
 	}
@@ -108,6 +110,12 @@ func (f OSCFloat32) WriteTo(out io.Writer) (int, error) {
 type OSCString string
 
 func ReadOSCString(in io.Reader) (OSCString, error) {
+	return readOSCString(in, DecodeOptions{})
+}
+
+// Reads an OSC-string, with the leniency allowed by the options (which must
+// already have Strict applied).
+func readOSCString(in io.Reader, opts DecodeOptions) (OSCString, error) {
 	var s []byte
 
 	var buf [1]byte
@@ -123,11 +131,11 @@ func ReadOSCString(in io.Reader) (OSCString, error) {
 	}
 
 	if err != nil && err != io.EOF {
-		return "", OSCReadErrorf("failed to read OSC-string: %v", err)
+		return "", OSCReadErrorf("failed to read OSC-string: %w", err)
 	}
 
 	if n == 0 {
-		return "", OSCReadErrorf("reached end of input before null terminator")
+		return "", OSCReadErrorf("reached end of input before null terminator").withKind(ErrTruncated)
 	}
 
 	// Then discard null padding (OSC-strings are supposed to be padded to four
@@ -136,14 +144,27 @@ func ReadOSCString(in io.Reader) (OSCString, error) {
 	for i := len(s) + 1; i % 4 != 0; i++ {
 		n, err = in.Read(buf[:])
 		if err != nil && err != io.EOF {
-			return "", OSCReadErrorf("failed to read OSC-string from input: %v", err)
+			return "", OSCReadErrorf("failed to read OSC-string from input: %w", err)
+		}
+		if n == 0 && opts.AllowUnpaddedTail {
+			break
 		}
-		if n == 0 || buf[0] != 0 {
-			return "", OSCReadErrorf("OSC-string was not padded properly")
+		if n == 0 {
+			return "", OSCReadErrorf("reached end of input before OSC-string padding").withKind(ErrTruncated)
+		}
+		if buf[0] != 0 {
+			return "", OSCReadErrorf("OSC-string was not padded properly").withKind(ErrBadPadding)
 		}
 	}
 
 	os := OSCString(s)
+	if opts.AllowUTF8Strings {
+		if !utf8.Valid(s) {
+			return os, OSCReadErrorf("invalid UTF-8 in string \"%s\"", s)
+		}
+		return os, nil
+	}
+
 	return os, os.Valid()
 }
 
@@ -183,25 +204,47 @@ func (s OSCString) WriteTo(out io.Writer) (int, error) {
 // a multiple of 32.
 type OSCBlob []byte
 
+// The largest blob ReadOSCBlob will accept, and the default for
+// DecodeOptions.MaxBlobSize. The blob size comes from the input, so without a
+// limit a single hostile packet could make the reader allocate up to 2GB.
+const OSC_MAX_BLOB_SIZE = 16 * 1024 * 1024
+
 func ReadOSCBlob(in io.Reader) (OSCBlob, error) {
+	return ReadOSCBlobLimit(in, OSC_MAX_BLOB_SIZE)
+}
+
+// Reads a blob, rejecting it if the size is larger than max bytes.
+func ReadOSCBlobLimit(in io.Reader, max int) (OSCBlob, error) {
 	size, err := ReadOSCInt32(in)
 
 	if err != nil {
-		return nil, OSCReadErrorf("failed to read blob size: %s", err)
+		return nil, OSCReadErrorf("failed to read blob size: %w", err)
 	}
-	
-	buffer := make([]byte, size)
-	n, err := in.Read(buffer)
 
-	if err != nil {
-		return nil, OSCReadErrorf("failed to read blob: %s", err)
+	if size < 0 {
+		return nil, OSCReadErrorf("invalid blob size %d", size)
+	}
+
+	if int(size) > max {
+		return nil, OSCReadErrorf("blob size %d exceeds maximum of %d bytes", size, max).withKind(ErrTooLarge)
 	}
 
-	if n != int(size) {
-		return nil, OSCReadErrorf("failed to read complete blob, got %d bytes out of %d", n, size)
+	// Read the data and padding together; io.ReadFull keeps reading until it
+	// has everything, for readers that return less than was asked for.
+	padded := (int(size) + OSC_BYTE_ALIGNMENT - 1) / OSC_BYTE_ALIGNMENT * OSC_BYTE_ALIGNMENT
+	buffer := make([]byte, padded)
+
+	if n, err := io.ReadFull(in, buffer); err != nil {
+		return nil, OSCReadErrorf("failed to read complete blob, got %d bytes out of %d: %w", n, padded, err)
 	}
 
-	return OSCBlob(buffer), nil
+	for _, b := range buffer[size:] {
+		if b != 0 {
+			return nil, OSCReadErrorf("blob was not padded properly").withKind(ErrBadPadding)
+		}
+	}
+
+	return OSCBlob(buffer[:size:size]), nil
 }
 
 func (s OSCBlob) Tag() OSCTypeTag {
@@ -218,41 +261,404 @@ func (b OSCBlob) WriteTo(out io.Writer) (int, error) {
 	}
 
 	n, err := out.Write([]byte(b))
+	if err != nil {
+		return n + 4, err
+	}
 
-	for n % 4 != 0 {
-		out.Write([]byte{0})
-		n++
+	if pad := (OSC_BYTE_ALIGNMENT - n % OSC_BYTE_ALIGNMENT) % OSC_BYTE_ALIGNMENT; pad > 0 {
+		p, err := out.Write(make([]byte, pad))
+		return n + p + 4, err
 	}
 
-	return n + 4, err
+	return n + 4, nil
 }
 
-// An OSC address pattern is an OSC-string with some additional restrictions.
-type OSCAddressPattern OSCString
+// 64-bit big-endian two's complement integer.
+type OSCInt64 int64
 
-func (s OSCAddressPattern) Valid() error {
-	// An Address Pattern is an OSC-string starting with "/".
+func ReadOSCInt64(in io.Reader) (OSCInt64, error) {
+	var out OSCInt64
+	if err := binary.Read(in, binary.BigEndian, &out); err != nil {
+		return 0, OSCReadErrorf("failed to read int64: %w", err)
+	} else {
+		return out, nil
+	}
+}
+
+func (i OSCInt64) Tag() OSCTypeTag {
+	return OSC_ETYPE_INT64
+}
+
+func (i OSCInt64) Valid() error {
+	return nil
+}
+
+func (i OSCInt64) WriteTo(out io.Writer) (int, error) {
+	return 8, binary.Write(out, binary.BigEndian, int64(i))
+}
+
+// 64-bit big-endian IEEE 754 floating point number.
+type OSCFloat64 float64
+
+func ReadOSCFloat64(in io.Reader) (OSCFloat64, error) {
+	var out OSCFloat64
+	if err := binary.Read(in, binary.BigEndian, &out); err != nil {
+		return 0, OSCReadErrorf("failed to read float64: %w", err)
+	} else {
+		return out, nil
+	}
+}
+
+func (f OSCFloat64) Tag() OSCTypeTag {
+	return OSC_ETYPE_FLOAT64
+}
+
+func (f OSCFloat64) Valid() error {
+	return nil
+}
+
+func (f OSCFloat64) WriteTo(out io.Writer) (int, error) {
+	return 8, binary.Write(out, binary.BigEndian, float64(f))
+}
+
+// 64-bit big-endian fixed-point NTP timestamp. The upper 32 bits are the
+// number of seconds since midnight on January 1, 1900, and the lower 32 bits
+// are fractions of a second.
+type OSCTimetag uint64
+
+func ReadOSCTimetag(in io.Reader) (OSCTimetag, error) {
+	var out OSCTimetag
+	if err := binary.Read(in, binary.BigEndian, &out); err != nil {
+		return 0, OSCReadErrorf("failed to read timetag: %w", err)
+	} else {
+		return out, nil
+	}
+}
+
+func (t OSCTimetag) Tag() OSCTypeTag {
+	return OSC_ETYPE_TIMETAG
+}
+
+func (t OSCTimetag) Valid() error {
+	return nil
+}
+
+func (t OSCTimetag) WriteTo(out io.Writer) (int, error) {
+	return 8, binary.Write(out, binary.BigEndian, uint64(t))
+}
+
+// Alternate string type, used by systems that distinguish "symbols" from
+// strings. Encoded exactly like an OSC-string.
+type OSCStringAlt OSCString
+
+func ReadOSCStringAlt(in io.Reader) (OSCStringAlt, error) {
+	s, err := ReadOSCString(in)
+	return OSCStringAlt(s), err
+}
+
+func (s OSCStringAlt) Tag() OSCTypeTag {
+	return OSC_ETYPE_STRING_ALT
+}
+
+func (s OSCStringAlt) Valid() error {
+	return OSCString(s).Valid()
+}
+
+func (s OSCStringAlt) WriteTo(out io.Writer) (int, error) {
+	return OSCString(s).WriteTo(out)
+}
+
+// An ASCII character, sent as 32 bits (the character in the low byte).
+type OSCChar byte
+
+func ReadOSCChar(in io.Reader) (OSCChar, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(in, buf[:]); err != nil {
+		return 0, OSCReadErrorf("failed to read char: %w", err)
+	}
+
+	c := OSCChar(buf[3])
+	return c, c.Valid()
+}
+
+func (c OSCChar) Tag() OSCTypeTag {
+	return OSC_ETYPE_CHAR
+}
+
+func (c OSCChar) Valid() error {
+	if c > 127 {
+		return OSCArgumentErrorf("non-ascii character 0x%x", byte(c))
+	}
+
+	return nil
+}
+
+func (c OSCChar) WriteTo(out io.Writer) (int, error) {
+	return out.Write([]byte{0, 0, 0, byte(c)})
+}
+
+// 32-bit RGBA color, one byte per channel.
+type OSCRGBA struct {
+	R, G, B, A uint8
+}
+
+func ReadOSCRGBA(in io.Reader) (OSCRGBA, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(in, buf[:]); err != nil {
+		return OSCRGBA{}, OSCReadErrorf("failed to read RGBA color: %w", err)
+	}
+
+	return OSCRGBA{buf[0], buf[1], buf[2], buf[3]}, nil
+}
+
+func (c OSCRGBA) Tag() OSCTypeTag {
+	return OSC_ETYPE_RGBA
+}
+
+func (c OSCRGBA) Valid() error {
+	return nil
+}
+
+func (c OSCRGBA) WriteTo(out io.Writer) (int, error) {
+	return out.Write([]byte{c.R, c.G, c.B, c.A})
+}
+
+// 4-byte MIDI message. Bytes from MSB to LSB are: port id, status byte,
+// data1, data2.
+type OSCMIDI struct {
+	Port, Status, Data1, Data2 uint8
+}
+
+func ReadOSCMIDI(in io.Reader) (OSCMIDI, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(in, buf[:]); err != nil {
+		return OSCMIDI{}, OSCReadErrorf("failed to read MIDI message: %w", err)
+	}
+
+	return OSCMIDI{buf[0], buf[1], buf[2], buf[3]}, nil
+}
+
+func (m OSCMIDI) Tag() OSCTypeTag {
+	return OSC_ETYPE_MIDI
+}
+
+func (m OSCMIDI) Valid() error {
+	return nil
+}
+
+func (m OSCMIDI) WriteTo(out io.Writer) (int, error) {
+	return out.Write([]byte{m.Port, m.Status, m.Data1, m.Data2})
+}
+
+// The remaining extended types carry no argument data; the value is conveyed
+// entirely by the type tag, so reading them consumes nothing from the input.
+
+// True ('T') or False ('F'), depending on the value.
+type OSCBool bool
+
+func ReadOSCBool(in io.Reader, tag OSCTypeTag) (OSCBool, error) {
+	switch tag {
+	case OSC_ETYPE_TRUE:
+		return true, nil
+	case OSC_ETYPE_FALSE:
+		return false, nil
+	default:
+		return false, OSCReadErrorf("not a boolean type tag: '%c'", tag)
+	}
+}
+
+func (b OSCBool) Tag() OSCTypeTag {
+	if b {
+		return OSC_ETYPE_TRUE
+	} else {
+		return OSC_ETYPE_FALSE
+	}
+}
+
+func (b OSCBool) Valid() error {
+	return nil
+}
+
+func (b OSCBool) WriteTo(out io.Writer) (int, error) {
+	return 0, nil
+}
+
+// Nil ('N').
+type OSCNil struct{}
+
+func ReadOSCNil(in io.Reader) (OSCNil, error) {
+	return OSCNil{}, nil
+}
+
+func (n OSCNil) Tag() OSCTypeTag {
+	return OSC_ETYPE_NIL
+}
+
+func (n OSCNil) Valid() error {
+	return nil
+}
+
+func (n OSCNil) WriteTo(out io.Writer) (int, error) {
+	return 0, nil
+}
+
+// Infinitum ('I').
+type OSCInfinity struct{}
+
+func ReadOSCInfinity(in io.Reader) (OSCInfinity, error) {
+	return OSCInfinity{}, nil
+}
+
+func (i OSCInfinity) Tag() OSCTypeTag {
+	return OSC_ETYPE_INFINITY
+}
+
+func (i OSCInfinity) Valid() error {
+	return nil
+}
+
+func (i OSCInfinity) WriteTo(out io.Writer) (int, error) {
+	return 0, nil
+}
+
+// An array of arguments, enclosed by '[' and ']' in the tag string. The array
+// itself contributes no data; each of its elements is written in turn.
+// Arrays may be nested.
+type OSCArray []OSCArg
+
+// Tag returns the opening bracket only; use TypeTags to get the complete tag
+// sequence for the array and its elements.
+func (a OSCArray) Tag() OSCTypeTag {
+	return OSC_ETYPE_ARRAY_START
+}
+
+// TypeTags returns the bracketed tag sequence for the array, including the
+// tags of all of its (possibly nested) elements.
+func (a OSCArray) TypeTags() string {
+	return string(appendTypeTags(nil, a))
+}
+
+func (a OSCArray) Valid() error {
+	for i, arg := range a {
+		if arg == nil {
+			return OSCArgumentErrorf("nil element at position %d in array", i)
+		}
+
+		if err := arg.Valid(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a OSCArray) WriteTo(out io.Writer) (int, error) {
+	total := 0
+
+	for _, arg := range a {
+		if n, err := arg.WriteTo(out); err != nil {
+			return total+n, err
+		} else {
+			total += n
+		}
+	}
+
+	return total, nil
+}
+
+// Appends the type tag(s) for an argument to a tag string. Arrays contribute
+// their brackets as well as the tags of all their elements, and raw arguments
+// all of their tags.
+func appendTypeTags(tags []byte, arg OSCArg) []byte {
+	switch a := arg.(type) {
+	case OSCArray:
+		tags = append(tags, byte(OSC_ETYPE_ARRAY_START))
+		for _, elem := range a {
+			tags = appendTypeTags(tags, elem)
+		}
+		return append(tags, byte(OSC_ETYPE_ARRAY_END))
+	case OSCRaw:
+		return append(tags, a.Tags...)
+	}
+
+	return append(tags, byte(arg.Tag()))
+}
+
+// An OSC address is the full path to a single OSC method, e.g.
+// "/mixer/3/mute". Addresses are OSC-strings with some additional
+// restrictions; in particular, they may not contain any of the wildcard
+// characters that are allowed in address patterns.
+type OSCAddress OSCString
+
+func (s OSCAddress) Valid() error {
+	// An Address is an OSC-string starting with "/".
 	if err := OSCString(s).Valid(); err != nil {
-		return err
+		return invalidAddress(err)
 	}
 
 	// At this point, the string has already been validated to contain only
 	// ASCII characters, so it's safe to cast the first rune to a byte.
 	if len(s) == 0 || s[0] != byte('/') {
-		return OSCArgumentErrorf("OSCAddressPattern must start with a forward slash")
+		return OSCArgumentErrorf("OSCAddress must start with a forward slash").withKind(ErrInvalidAddress)
 	}
 
-	// Certain ASCII characters are disallowed in address patterns. Technically,
+	// Certain ASCII characters are disallowed in addresses. Technically,
 	// this is the list of disallowed characters for symbolic names, but I also
 	// allow forward slashes here (the name separator).
 	for i := 1; i < len(s); i++ {
 		for _, invalid := range(" #*,?[]{}") {
 			if s[i] == byte(invalid) {
-				return OSCArgumentErrorf("disallowed character '%s' found at position %d in string \"%s\"", invalid, i, s)
+				return OSCArgumentErrorf("disallowed character '%c' found at position %d in string \"%s\"", invalid, i, s).withKind(ErrInvalidAddress)
+			}
+		}
+	}
+
+	// Two slashes in a row would be read as the path traversal wildcard.
+	if i := strings.Index(string(s), "//"); i >= 0 {
+		return OSCArgumentErrorf("empty address part at position %d in string \"%s\"", i+1, s).withKind(ErrInvalidAddress)
+	}
+
+	return nil
+}
+
+func (s OSCAddress) WriteTo(out io.Writer) (int, error) {
+	return OSCString(s).WriteTo(out)
+}
+
+// An OSC address pattern is an OSC-string with some additional restrictions.
+// Unlike an OSCAddress, it may contain wildcards (see Pattern) that match any
+// number of addresses.
+type OSCAddressPattern OSCString
+
+func (s OSCAddressPattern) Valid() error {
+	// An Address Pattern is an OSC-string starting with "/".
+	if err := OSCString(s).Valid(); err != nil {
+		return invalidAddress(err)
+	}
+
+	if len(s) == 0 || s[0] != byte('/') {
+		return OSCArgumentErrorf("OSCAddressPattern must start with a forward slash").withKind(ErrInvalidAddress)
+	}
+
+	// Spaces and hashes are never allowed, wildcards or not. (Commas are only
+	// allowed inside braces, which is checked by CompilePattern.)
+	for i := 1; i < len(s); i++ {
+		for _, invalid := range(" #") {
+			if s[i] == byte(invalid) {
+				return OSCArgumentErrorf("disallowed character '%c' found at position %d in string \"%s\"", invalid, i, s).withKind(ErrInvalidAddress)
 			}
 		}
 	}
 
+	// Any wildcards have to be well-formed, i.e. brackets and braces have to
+	// be balanced. The pattern is only checked, not compiled, so validating it
+	// doesn't allocate.
+	if strings.ContainsAny(string(s), "*?[]{},") {
+		if err := checkPattern(string(s)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,192 @@
+package gosc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+)
+
+// Default size of the UDP receive buffer; large enough for any datagram.
+const OSC_UDP_BUFFER_SIZE = 65535
+
+// Sends OSC packets to a single remote address over UDP. Each packet is sent as
+// exactly one datagram.
+//
+// A UDPClient is safe for concurrent use.
+type UDPClient struct {
+	mu   sync.Mutex
+	conn *net.UDPConn
+	buf  bytes.Buffer
+}
+
+// NewUDPClient creates a client that sends to the specified address.
+func NewUDPClient(addr *net.UDPAddr) (*UDPClient, error) {
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UDPClient{conn: conn}, nil
+}
+
+// DialUDP resolves the address (e.g. "localhost:9000") and creates a client
+// that sends to it.
+func DialUDP(address string) (*UDPClient, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewUDPClient(addr)
+}
+
+// Send encodes a message with WriteMessage and sends it as a single datagram.
+func (c *UDPClient) Send(address OSCAddressPattern, args...OSCArg) error {
+	return c.SendPacket(Message{address, args})
+}
+
+// SendPacket encodes a message or bundle and sends it as a single datagram.
+func (c *UDPClient) SendPacket(packet OSCPacket) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The packet is encoded in full first; writing it piecemeal to the
+	// connection would split it across several datagrams.
+	c.buf.Reset()
+	if _, err := packet.WriteTo(&c.buf); err != nil {
+		return err
+	}
+
+	_, err := c.conn.Write(c.buf.Bytes())
+	return err
+}
+
+// LocalAddr returns the local address the client is sending from.
+func (c *UDPClient) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the address the client is sending to.
+func (c *UDPClient) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *UDPClient) Close() error {
+	return c.conn.Close()
+}
+
+// Handles a message received from a remote address.
+type UDPHandlerFunc func(msg Message, from *net.UDPAddr)
+
+// Receives OSC packets over UDP. Each datagram is decoded with ReadPacket and
+// passed to the PacketHandler, or (message by message) to the Handler.
+type UDPServer struct {
+	// Addr is the local address to listen on (e.g. ":9000"), used by
+	// ListenAndServe.
+	Addr string
+
+	// Handler is called for every message received, in the receive loop.
+	// Messages in bundles are passed to it one by one, in order, and their
+	// timetags are ignored; use PacketHandler (with a Scheduler, say) to
+	// honor them.
+	Handler UDPHandlerFunc
+
+	// PacketHandler, if set, is called with every packet received (either a
+	// Message or an OSCBundle) in place of Handler.
+	PacketHandler func(packet OSCPacket, from *net.UDPAddr)
+
+	// ErrorHandler, if set, is called for every datagram that can't be decoded.
+	// Otherwise, such datagrams are silently dropped.
+	ErrorHandler func(err error, from *net.UDPAddr)
+
+	// BufferSize is the largest datagram that can be received; anything larger
+	// is truncated (and will fail to decode). Defaults to OSC_UDP_BUFFER_SIZE.
+	BufferSize int
+
+	// ReadBuffer, if non-zero, sets the size of the operating system's receive
+	// buffer for the socket.
+	ReadBuffer int
+
+	// Decode sets the options for decoding datagrams, including the registry
+	// of custom types and the largest blob to accept.
+	Decode DecodeOptions
+}
+
+// ListenAndServe listens on s.Addr and handles incoming messages until the
+// context is cancelled.
+func (s *UDPServer) ListenAndServe(ctx context.Context) error {
+	addr, err := net.ResolveUDPAddr("udp", s.Addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, conn)
+}
+
+// Serve handles incoming messages on an existing connection until the context
+// is cancelled (returning the context's error) or the connection fails. The
+// connection is closed when Serve returns.
+func (s *UDPServer) Serve(ctx context.Context, conn *net.UDPConn) error {
+	defer conn.Close()
+
+	if s.ReadBuffer > 0 {
+		if err := conn.SetReadBuffer(s.ReadBuffer); err != nil {
+			return err
+		}
+	}
+
+	size := s.BufferSize
+	if size <= 0 {
+		size = OSC_UDP_BUFFER_SIZE
+	}
+	buf := make([]byte, size)
+
+	// Closing the connection is the only way to interrupt a blocked read.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	opts := s.Decode.effective()
+
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		// A datagram holds exactly one packet, so anything after it is an
+		// error, as it is for a frame on a stream.
+		r := bytes.NewReader(buf[:n])
+		packet, err := readPacket(r, 0, opts)
+		if err == nil && r.Len() != 0 {
+			err = OSCReadErrorf("datagram has %d bytes, but the message used %d", n, n - r.Len())
+		}
+		if err != nil {
+			if s.ErrorHandler != nil {
+				s.ErrorHandler(err, from)
+			}
+			continue
+		}
+
+		if s.PacketHandler != nil {
+			s.PacketHandler(packet, from)
+		} else if s.Handler != nil {
+			eachMessage(packet, func(m Message) { s.Handler(m, from) })
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package gosc
+
+import (
+	"context"
+	"net"
+	. "testing"
+	"time"
+)
+
+// Starts a UDP server on a random localhost port, returning its address, a
+// channel of received messages, a channel of decode errors, and a channel that
+// receives Serve's return value.
+func startUDPServer(t *T, ctx context.Context, bufferSize int) (*net.UDPAddr, chan Message, chan error, chan error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	messages := make(chan Message, 10)
+	errors := make(chan error, 10)
+	result := make(chan error, 1)
+
+	server := &UDPServer{
+		Handler: func(m Message, from *net.UDPAddr) {
+			messages <- m
+		},
+		ErrorHandler: func(err error, from *net.UDPAddr) {
+			errors <- err
+		},
+		BufferSize: bufferSize,
+	}
+
+	go func() {
+		result <- server.Serve(ctx, conn)
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr), messages, errors, result
+}
+
+func TestUDPClientServer(t *T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, messages, _, _ := startUDPServer(t, ctx, 0)
+
+	client, err := NewUDPClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer client.Close()
+
+	expectNil(t, client.Send(OSCAddressPattern("/a"), OSCInt32(1), OSCString("two")))
+	expectNil(t, client.Send(OSCAddressPattern("/b")))
+
+	for _, expected := range []Message{
+		{OSCAddressPattern("/a"), []OSCArg{OSCInt32(1), OSCString("two")}},
+		{OSCAddressPattern("/b"), []OSCArg{}},
+	} {
+		select {
+		case m := <-messages:
+			expectSame(t, expected, m)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", expected.Address)
+		}
+	}
+}
+
+func TestUDPServerBundles(t *T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, messages, _, _ := startUDPServer(t, ctx, 0)
+
+	client, err := NewUDPClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer client.Close()
+
+	bundle := OSCBundle{
+		Timetag: OSC_TIMETAG_IMMEDIATELY,
+		Elements: []OSCPacket{
+			Message{OSCAddressPattern("/a"), []OSCArg{OSCInt32(1)}},
+			OSCBundle{OSC_TIMETAG_IMMEDIATELY, []OSCPacket{Message{OSCAddressPattern("/b"), []OSCArg{}}}},
+		},
+	}
+	expectNil(t, client.SendPacket(bundle))
+
+	for _, expected := range []OSCAddressPattern{"/a", "/b"} {
+		select {
+		case m := <-messages:
+			expectSame(t, expected, m.Address)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+
+	// With a PacketHandler, the bundle is passed on whole.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	packets := make(chan OSCPacket, 1)
+	server := &UDPServer{PacketHandler: func(p OSCPacket, from *net.UDPAddr) {
+		packets <- p
+	}}
+	go server.Serve(ctx, conn)
+
+	other, err := NewUDPClient(conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer other.Close()
+
+	expectNil(t, other.SendPacket(bundle))
+
+	select {
+	case p := <-packets:
+		expectSame(t, OSCPacket(bundle), p)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for bundle")
+	}
+}
+
+func TestUDPServerErrors(t *T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, messages, errors, _ := startUDPServer(t, ctx, 8)
+
+	client, err := NewUDPClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer client.Close()
+
+	// Too large for the 8 byte buffer, so it gets truncated.
+	expectNil(t, client.Send(OSCAddressPattern("/too/long"), OSCInt32(1)))
+	// Still fits.
+	expectNil(t, client.Send(OSCAddressPattern("/ok")))
+
+	select {
+	case <-errors:
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for decode error")
+	}
+
+	select {
+	case m := <-messages:
+		expectSame(t, OSCAddressPattern("/ok"), m.Address)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for message")
+	}
+}
+
+// A message followed by extra bytes is rejected, not truncated.
+func TestUDPServerTrailingData(t *T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, messages, errors, _ := startUDPServer(t, ctx, 0)
+
+	client, err := NewUDPClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer client.Close()
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte{47, 97, 0, 0, 44, 0, 0, 0, 1, 2, 3, 4})
+	expectNil(t, client.Send(OSCAddressPattern("/ok")))
+
+	select {
+	case err := <-errors:
+		if _, ok := err.(OSCReadError); !ok {
+			t.Errorf("expected an OSCReadError, got %#v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for trailing data error")
+	}
+
+	select {
+	case m := <-messages:
+		expectSame(t, OSCAddressPattern("/ok"), m.Address)
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for message")
+	}
+}
+
+func TestUDPServerCancel(t *T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, _, _, result := startUDPServer(t, ctx, 0)
+
+	cancel()
+
+	select {
+	case err := <-result:
+		expectSame(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatalf("server did not stop after cancellation")
+	}
+}
+
+func TestUDPClientInvalidMessage(t *T) {
+	client, err := DialUDP("127.0.0.1:9")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer client.Close()
+
+	if _, ok := client.Send(OSCAddressPattern("bad")).(OSCArgumentError); !ok {
+		t.Errorf("expected an OSCArgumentError for an invalid address")
+	}
+}
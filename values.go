@@ -0,0 +1,88 @@
+package gosc
+
+import (
+	"io"
+	"reflect"
+)
+
+// ValueOptions controls how native Go values are converted to arguments.
+type ValueOptions struct {
+	// Send float64 values as float32 ('f') instead of float64 ('d').
+	Float32 bool
+}
+
+// WriteValues writes an OSC message whose arguments are inferred from native
+// Go values, so that they don't have to be wrapped in OSCArg types:
+//
+//	WriteValues(out, "/mixer/3/fader", 0.75, "Kick", true)
+//
+// Integers of any type (including int64) are sent as int32, or as int64 if
+// they don't fit; float64 is sent as a double, bool as T or F, nil as N and
+// []byte as a blob. See Marshal for the full list of conversions. A value that
+// can't be represented returns an OSCArgumentError.
+func WriteValues(out io.Writer, address string, values...interface{}) (int, error) {
+	return WriteValuesWith(out, address, ValueOptions{}, values...)
+}
+
+// WriteValuesWith is like WriteValues, with options for the conversion.
+func WriteValuesWith(out io.Writer, address string, opts ValueOptions, values...interface{}) (int, error) {
+	args, err := ValuesToArgsWith(opts, values...)
+	if err != nil {
+		return 0, err
+	}
+
+	return WriteMessage(out, OSCAddressPattern(address), args...)
+}
+
+// ValuesToArgs converts native Go values to arguments, in the same way as
+// WriteValues.
+func ValuesToArgs(values...interface{}) ([]OSCArg, error) {
+	return ValuesToArgsWith(ValueOptions{}, values...)
+}
+
+// ValuesToArgsWith is like ValuesToArgs, with options for the conversion.
+func ValuesToArgsWith(opts ValueOptions, values...interface{}) ([]OSCArg, error) {
+	args := make([]OSCArg, len(values))
+
+	for i, value := range values {
+		if value == nil {
+			args[i] = OSCNil{}
+			continue
+		}
+
+		// Values that are already arguments are used as-is.
+		if arg, ok := value.(OSCArg); ok {
+			args[i] = arg
+			continue
+		}
+
+		arg, err := valueToArg(reflect.ValueOf(value), 0, true)
+		if err != nil {
+			return nil, OSCArgumentErrorf("value %d: %w", i, err)
+		}
+
+		if opts.Float32 {
+			arg = narrowFloats(arg)
+		}
+
+		args[i] = arg
+	}
+
+	return args, nil
+}
+
+// Replaces float64 arguments (including those in arrays) with float32.
+func narrowFloats(arg OSCArg) OSCArg {
+	switch a := arg.(type) {
+	case OSCFloat64:
+		return OSCFloat32(a)
+	case OSCArray:
+		narrowed := make(OSCArray, len(a))
+		for i, elem := range a {
+			narrowed[i] = narrowFloats(elem)
+		}
+		return narrowed
+	}
+
+	return arg
+}
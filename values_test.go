@@ -0,0 +1,60 @@
+package gosc
+
+import (
+	"bytes"
+	"math"
+	. "testing"
+)
+
+func TestValuesToArgs(t *T) {
+	args, err := ValuesToArgs(1, int64(2), int64(math.MaxInt32 + 1), math.MaxInt32 + 1, -math.MaxInt32 - 2, uint8(3), 0.5, float32(0.25),
+		"s", true, false, nil, []byte{1}, []int64{4, 1 << 40}, OSCRGBA{1, 2, 3, 4})
+	expectNil(t, err)
+
+	expectSame(t, []OSCArg{
+		OSCInt32(1), OSCInt32(2), OSCInt64(math.MaxInt32 + 1), OSCInt64(math.MaxInt32 + 1), OSCInt64(-math.MaxInt32 - 2), OSCInt32(3),
+		OSCFloat64(0.5), OSCFloat32(0.25), OSCString("s"), OSCBool(true), OSCBool(false), OSCNil{},
+		OSCBlob([]byte{1}), OSCArray{OSCInt32(4), OSCInt64(1 << 40)}, OSCRGBA{1, 2, 3, 4},
+	}, args)
+}
+
+func TestValuesToArgsFloat32(t *T) {
+	args, err := ValuesToArgsWith(ValueOptions{Float32: true}, 0.5, []float64{1.5}, OSCFloat64(2))
+	expectNil(t, err)
+
+	// Explicit OSCArgs are left alone.
+	expectSame(t, []OSCArg{OSCFloat32(0.5), OSCArray{OSCFloat32(1.5)}, OSCFloat64(2)}, args)
+}
+
+func TestValuesToArgsErrors(t *T) {
+	_, err := ValuesToArgs(1, make(chan int))
+	expectArgumentError(t, err, "value 1: unsupported type chan int")
+
+	_, err = ValuesToArgs(uint64(math.MaxUint64))
+	expectArgumentError(t, err, "value 0: 18446744073709551615 overflows int64")
+
+	_, err = ValuesToArgs(map[string]int{})
+	expectArgumentError(t, err, "unsupported type map[string]int")
+}
+
+func TestWriteValues(t *T) {
+	var expected, actual bytes.Buffer
+
+	WriteMessage(&expected, OSCAddressPattern("/a/b"), OSCInt32(1), OSCFloat64(2.5), OSCString("x"), OSCBool(true), OSCNil{})
+	_, err := WriteValues(&actual, "/a/b", 1, 2.5, "x", true, nil)
+	expectNil(t, err)
+	expectSame(t, expected.Bytes(), actual.Bytes())
+
+	expected.Reset()
+	actual.Reset()
+
+	WriteMessage(&expected, OSCAddressPattern("/a/b"), OSCFloat32(2.5))
+	_, err = WriteValuesWith(&actual, "/a/b", ValueOptions{Float32: true}, 2.5)
+	expectNil(t, err)
+	expectSame(t, expected.Bytes(), actual.Bytes())
+
+	actual.Reset()
+	_, err = WriteValues(&actual, "/a/b", struct{}{})
+	expectArgumentError(t, err, "unsupported type struct {}")
+	expectSame(t, 0, actual.Len())
+}